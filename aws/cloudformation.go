@@ -8,18 +8,12 @@ import (
 	"github.com/aws/aws-sdk-go/service/cloudformation"
 )
 
-var cfClient *cloudformation.CloudFormation
-
 type Cloudformation struct {
 	client *cloudformation.CloudFormation
 }
 
 func NewCloudformation(session *session.Session) Cloudformation {
-	if cfClient == nil {
-		cfClient = cloudformation.New(session)
-	}
-
-	return Cloudformation{client: cfClient}
+	return Cloudformation{client: cloudformation.New(session)}
 }
 
 func (c *Cloudformation) GetOutput(stackname string) (map[string]string, error) {
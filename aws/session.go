@@ -2,28 +2,40 @@ package aws
 
 import (
 	"os"
+	"sync"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/aws/session"
 )
 
-var ses *session.Session
+var (
+	sessions = map[string]*session.Session{}
+	mu       sync.Mutex
+)
 
+// NewSession returns the session of the region in cfg. A session is created
+// once per region. Without a region AWS_REGION is used
 func NewSession(cfg aws.Config) *session.Session {
-	if ses == nil {
-		if cfg.Retryer == nil {
-			cfg.Retryer = Retryer
-		}
+	mu.Lock()
+	defer mu.Unlock()
 
-		if cfg.Region == nil {
-			region := os.Getenv("AWS_REGION")
-			cfg.Region = &region
-		}
+	if cfg.Region == nil {
+		region := os.Getenv("AWS_REGION")
+		cfg.Region = &region
+	}
 
-		ses = session.Must(session.NewSession(&cfg))
+	if ses, ok := sessions[*cfg.Region]; ok {
+		return ses
 	}
 
+	if cfg.Retryer == nil {
+		cfg.Retryer = Retryer
+	}
+
+	ses := session.Must(session.NewSession(&cfg))
+	sessions[*cfg.Region] = ses
+
 	return ses
 }
 
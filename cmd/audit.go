@@ -0,0 +1,341 @@
+package cmd
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	c "github.com/adikari/safebox/v2/config"
+	"github.com/adikari/safebox/v2/store"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	RuleMaxAge              = "max-age"
+	RuleSecureString        = "secure-string"
+	RulePlaintextCredential = "plaintext-credential"
+	RuleDescription         = "description"
+)
+
+var (
+	auditFormat string
+
+	auditCmd = &cobra.Command{
+		Use:   "audit",
+		Short: "Checks configs and secrets against compliance rules",
+		RunE:  audit,
+	}
+
+	credentialName = regexp.MustCompile(`(?i)(password|passwd|secret|token|api[_-]?key|private[_-]?key|access[_-]?key|credential)`)
+
+	credentialValues = []struct {
+		name    string
+		pattern *regexp.Regexp
+	}{
+		{"an aws access key", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+		{"a private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+		{"a url with credentials", regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@]+:[^/\s@]+@`)},
+		{"a jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.`)},
+		{"a github token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36}\b`)},
+		{"a slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]+`)},
+		{"a stripe key", regexp.MustCompile(`\b[sr]k_live_[A-Za-z0-9]+`)},
+	}
+)
+
+func init() {
+	auditCmd.Flags().StringVarP(&auditFormat, "format", "f", "table", "output format (table, json, junit)")
+
+	rootCmd.AddCommand(auditCmd)
+}
+
+// AuditResult is the result of checking a key against a rule
+type AuditResult struct {
+	Name    string `json:"name"`
+	Rule    string `json:"rule"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+func audit(_ *cobra.Command, _ []string) error {
+	format := strings.ToLower(auditFormat)
+
+	if format != "table" && format != "json" && format != "junit" {
+		return errors.Errorf("unsupported audit format: %s", auditFormat)
+	}
+
+	config, err := loadConfig()
+
+	if err != nil {
+		return errors.Wrap(err, "failed to load config")
+	}
+
+	st, err := getStore(config.StoreConfig())
+
+	if err != nil {
+		return errors.Wrap(err, "failed to instantiate store")
+	}
+
+	existing, err := st.GetMany(config.All)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to read existing params")
+	}
+
+	results := auditConfigs(config, existing, time.Now())
+
+	switch format {
+	case "json":
+		err = writeAuditJson(results, os.Stdout)
+	case "junit":
+		err = writeAuditJunit(results, os.Stdout)
+	default:
+		writeAuditTable(results, os.Stdout)
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "failed to write audit results")
+	}
+
+	failures := countFailures(results)
+
+	if format == "table" {
+		PrintSummary(Summary{
+			Message: fmt.Sprintf("checks = %d, failures = %d", len(results), failures),
+			Config:  *config,
+		})
+	}
+
+	if failures > 0 {
+		return errors.Errorf("audit failed with %d failures", failures)
+	}
+
+	return nil
+}
+
+// auditConfigs checks every key in the config file. Only secrets need a
+// description. Secrets that are not in the store yet are only checked for one
+func auditConfigs(config *c.Config, existing []store.Config, now time.Time) []AuditResult {
+	stored := map[string]store.Config{}
+	for _, e := range existing {
+		stored[*e.Name] = e
+	}
+
+	all := append([]store.ConfigInput{}, config.All...)
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+
+	var results []AuditResult
+
+	for _, input := range all {
+		if input.Secret {
+			if s, ok := stored[input.Name]; ok {
+				results = append(results, checkSecureString(input, s))
+
+				if maxAge, ok := config.MaxAge(input.Name); ok {
+					results = append(results, checkMaxAge(input, s, maxAge, now))
+				}
+			}
+
+			results = append(results, checkDescription(input))
+		} else {
+			results = append(results, checkPlaintextCredential(input))
+		}
+	}
+
+	return results
+}
+
+func checkMaxAge(input store.ConfigInput, s store.Config, maxAge time.Duration, now time.Time) AuditResult {
+	r := AuditResult{Name: input.Name, Rule: RuleMaxAge, Passed: true}
+	age := now.Sub(s.Modified)
+
+	if age > maxAge {
+		r.Passed = false
+		r.Message = fmt.Sprintf("last modified %s, %s ago. max age is %s", s.Modified.Local().Format(TimeFormat), formatAge(age), formatAge(maxAge))
+	}
+
+	return r
+}
+
+func checkSecureString(input store.ConfigInput, s store.Config) AuditResult {
+	r := AuditResult{Name: input.Name, Rule: RuleSecureString, Passed: true}
+
+	if s.Type != "SecureString" {
+		r.Passed = false
+		r.Message = fmt.Sprintf("secret is stored as %s", s.Type)
+	}
+
+	return r
+}
+
+func checkPlaintextCredential(input store.ConfigInput) AuditResult {
+	r := AuditResult{Name: input.Name, Rule: RulePlaintextCredential, Passed: true}
+
+	for _, c := range credentialValues {
+		if c.pattern.MatchString(input.Value) {
+			r.Passed = false
+			r.Message = fmt.Sprintf("value looks like %s. move it to secret", c.name)
+			return r
+		}
+	}
+
+	if credentialName.MatchString(input.Key()) && !isPlainValue(input.Value) {
+		r.Passed = false
+		r.Message = "name looks like a credential. move it to secret"
+	}
+
+	return r
+}
+
+func checkDescription(input store.ConfigInput) AuditResult {
+	r := AuditResult{Name: input.Name, Rule: RuleDescription, Passed: true}
+
+	if strings.TrimSpace(input.Description) == "" {
+		r.Passed = false
+		r.Message = "description is missing"
+	}
+
+	return r
+}
+
+// isPlainValue returns true for values that can not be credentials
+func isPlainValue(value string) bool {
+	if value == "" {
+		return true
+	}
+
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return true
+	}
+
+	if _, err := strconv.ParseBool(value); err == nil {
+		return true
+	}
+
+	return false
+}
+
+// formatAge formats the duration in days when it is at least a day
+func formatAge(d time.Duration) string {
+	if d >= 24*time.Hour {
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+
+	return d.Round(time.Second).String()
+}
+
+func countFailures(results []AuditResult) int {
+	n := 0
+	for _, r := range results {
+		if !r.Passed {
+			n++
+		}
+	}
+	return n
+}
+
+func writeAuditTable(results []AuditResult, out io.Writer) {
+	if countFailures(results) <= 0 {
+		fmt.Fprintln(out, "no failures found")
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 8, 2, '\t', 0)
+
+	fmt.Fprintln(w, "Name\tRule\tMessage")
+
+	for _, r := range results {
+		if !r.Passed {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", r.Name, r.Rule, r.Message)
+		}
+	}
+
+	fmt.Fprintln(w, "---")
+	w.Flush()
+}
+
+func writeAuditJson(results []AuditResult, w io.Writer) error {
+	if results == nil {
+		results = []AuditResult{}
+	}
+
+	d, err := json.MarshalIndent(results, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w, string(d))
+	return err
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+}
+
+// writeAuditJunit writes a test suite per rule with a test case per key
+func writeAuditJunit(results []AuditResult, w io.Writer) error {
+	suites := junitTestSuites{Name: "safebox audit"}
+	index := map[string]int{}
+
+	for _, r := range results {
+		i, ok := index[r.Rule]
+
+		if !ok {
+			i = len(suites.Suites)
+			index[r.Rule] = i
+			suites.Suites = append(suites.Suites, junitTestSuite{Name: r.Rule})
+		}
+
+		tc := junitTestCase{Name: r.Name, Classname: "safebox." + r.Rule}
+
+		if !r.Passed {
+			tc.Failure = &junitFailure{Message: r.Message, Type: r.Rule}
+			suites.Suites[i].Failures++
+			suites.Failures++
+		}
+
+		suites.Suites[i].Tests++
+		suites.Suites[i].Cases = append(suites.Suites[i].Cases, tc)
+		suites.Tests++
+	}
+
+	d, err := xml.MarshalIndent(suites, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, d)
+	return err
+}
@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	c "github.com/adikari/safebox/v2/config"
+	"github.com/adikari/safebox/v2/store"
+	"github.com/adikari/safebox/v2/store/storetest"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testConfig = `service: svc
+provider: ssm
+prefix: /svc/
+
+cloudformation-stacks:
+  - "svc-{{.stage}}"
+
+tags:
+  team: core
+
+config:
+  defaults:
+    DB_HOST: "db.{{.stage}}"
+    QUEUE_URL: "https://sqs.{{.region}}.amazonaws.com/{{.account}}/{{.QueueName}}"
+
+secret:
+  defaults:
+    API_TOKEN:
+      generator: uuid
+    ADMIN_HASH:
+      generator:
+        type: bcrypt
+        cost: 4
+    ADMIN_HASH_PASSWORD: password of ADMIN_HASH
+`
+
+// fakeAwsEnv returns the outputs of the svc-dev stack
+type fakeAwsEnv struct{}
+
+func (fakeAwsEnv) Region() string { return "us-east-1" }
+
+func (fakeAwsEnv) Account() (string, error) { return "123456789012", nil }
+
+func (fakeAwsEnv) StackOutputs(stacks []string) (map[string]string, error) {
+	if len(stacks) != 1 || stacks[0] != "svc-dev" {
+		return nil, fmt.Errorf("unexpected stacks %v", stacks)
+	}
+
+	return map[string]string{"QueueName": "svc-dev-queue"}, nil
+}
+
+// setup writes the config file and replaces the store and the aws env with
+// fakes that are shared by the commands run in the test
+func setup(t *testing.T) store.Store {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "safebox.yml")
+
+	if err := os.WriteFile(path, []byte(testConfig), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	st := store.NewSSMStoreWithClient(storetest.NewSSM(), store.SSMStoreOptions{})
+
+	originalStore, originalAwsEnv := getStore, getAwsEnv
+	getStore = func(store.StoreConfig) (store.Store, error) { return st, nil }
+	getAwsEnv = func(string) c.AwsEnv { return fakeAwsEnv{} }
+	t.Cleanup(func() { getStore, getAwsEnv = originalStore, originalAwsEnv })
+
+	pathToConfig = path
+
+	return st
+}
+
+// run executes the command with the flags reset to their defaults
+func run(t *testing.T, args ...string) {
+	t.Helper()
+
+	stage, vars = "", map[string]string{}
+	removeOrphans, prompt, dryRun = false, "", false
+	exportFormat, outputFile, keysToExport = "json", "", []string{}
+	rotateParams, rotateOlderThan, rotatePostHook = []string{}, "", ""
+	importFormat, inputFile, secretKeys = "json", "", []string{}
+
+	rootCmd.SetArgs(append(args, "--config", pathToConfig))
+
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("safebox %v failed: %v", args, err)
+	}
+}
+
+func TestDeployAndExport(t *testing.T) {
+	st := setup(t)
+
+	run(t, "deploy", "--stage", "dev")
+
+	token, err := st.Get(store.ConfigInput{Name: "/svc/API_TOKEN"})
+
+	if err != nil || *token.Value == "" {
+		t.Fatalf("expected generated API_TOKEN, got %v, %v", token, err)
+	}
+
+	output := filepath.Join(t.TempDir(), "out.json")
+	run(t, "export", "--stage", "dev", "--output-file", output)
+
+	data, err := os.ReadFile(output)
+
+	if err != nil {
+		t.Fatalf("failed to read export: %v", err)
+	}
+
+	var exported map[string]string
+
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatalf("failed to parse export %s: %v", data, err)
+	}
+
+	if exported["DB_HOST"] != "db.dev" || exported["API_TOKEN"] != *token.Value ||
+		exported["QUEUE_URL"] != "https://sqs.us-east-1.amazonaws.com/123456789012/svc-dev-queue" {
+		t.Errorf("unexpected export %v", exported)
+	}
+}
+
+func TestDeployRemovesOrphans(t *testing.T) {
+	st := setup(t)
+
+	if err := st.PutMany([]store.ConfigInput{{Name: "/svc/ORPHAN", Value: "orphan"}}); err != nil {
+		t.Fatalf("failed to put orphan: %v", err)
+	}
+
+	run(t, "deploy", "--stage", "dev")
+
+	if _, err := st.Get(store.ConfigInput{Name: "/svc/ORPHAN"}); err != nil {
+		t.Fatalf("expected orphan to be kept without --remove-orphans, got %v", err)
+	}
+
+	run(t, "deploy", "--stage", "dev", "--remove-orphans")
+
+	configs, err := st.GetByPath("/svc/")
+
+	if err != nil {
+		t.Fatalf("failed to list configs: %v", err)
+	}
+
+	names := map[string]bool{}
+	for _, c := range configs {
+		names[*c.Name] = true
+	}
+
+	if names["/svc/ORPHAN"] || !names["/svc/DB_HOST"] || !names["/svc/API_TOKEN"] || len(names) != 5 {
+		t.Errorf("expected only the configs of the file, got %v", names)
+	}
+}
+
+func TestImportKeepsTags(t *testing.T) {
+	st := setup(t)
+
+	run(t, "deploy", "--stage", "dev")
+
+	input := filepath.Join(t.TempDir(), "import.json")
+
+	if err := os.WriteFile(input, []byte(`{"DB_HOST": "db.imported", "EXTRA": "extra"}`), 0644); err != nil {
+		t.Fatalf("failed to write import file: %v", err)
+	}
+
+	run(t, "import", "--stage", "dev", "--input-file", input)
+
+	tags, err := st.(store.TagStore).GetTags([]store.ConfigInput{{Name: "/svc/DB_HOST"}, {Name: "/svc/EXTRA"}})
+
+	if err != nil {
+		t.Fatalf("failed to get tags: %v", err)
+	}
+
+	for _, name := range []string{"/svc/DB_HOST", "/svc/EXTRA"} {
+		if tags[name]["team"] != "core" {
+			t.Errorf("expected %s to be tagged team=core, got %v", name, tags[name])
+		}
+	}
+}
+
+func TestDeployBcryptWritesPassword(t *testing.T) {
+	st := setup(t)
+
+	run(t, "deploy", "--stage", "dev")
+
+	hash := assertPassword(t, st)
+
+	// only the hash is missing. the existing password is hashed again
+	if err := st.DeleteMany([]store.ConfigInput{{Name: "/svc/ADMIN_HASH"}}); err != nil {
+		t.Fatalf("failed to delete hash: %v", err)
+	}
+
+	password, _ := st.Get(store.ConfigInput{Name: "/svc/ADMIN_HASH_PASSWORD"})
+
+	run(t, "deploy", "--stage", "dev")
+
+	if assertPassword(t, st) == hash {
+		t.Errorf("expected a new hash")
+	}
+
+	if current, _ := st.Get(store.ConfigInput{Name: "/svc/ADMIN_HASH_PASSWORD"}); *current.Value != *password.Value {
+		t.Errorf("expected the existing password to be kept")
+	}
+
+	run(t, "rotate", "--stage", "dev", "--param", "ADMIN_HASH_PASSWORD")
+
+	if current, _ := st.Get(store.ConfigInput{Name: "/svc/ADMIN_HASH_PASSWORD"}); *current.Value == *password.Value {
+		t.Errorf("expected the password to be rotated")
+	}
+
+	assertPassword(t, st)
+}
+
+// assertPassword checks the bcrypt hash matches its password and returns it
+func assertPassword(t *testing.T, st store.Store) string {
+	t.Helper()
+
+	hash, err := st.Get(store.ConfigInput{Name: "/svc/ADMIN_HASH"})
+
+	if err != nil {
+		t.Fatalf("expected ADMIN_HASH, got %v", err)
+	}
+
+	password, err := st.Get(store.ConfigInput{Name: "/svc/ADMIN_HASH_PASSWORD"})
+
+	if err != nil {
+		t.Fatalf("expected ADMIN_HASH_PASSWORD, got %v", err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(*hash.Value), []byte(*password.Value)); err != nil {
+		t.Fatalf("expected hash of the password, got %v", err)
+	}
+
+	return *hash.Value
+}
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	c "github.com/adikari/safebox/v2/config"
+	"github.com/adikari/safebox/v2/store"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	againstStage string
+
+	compareCmd = &cobra.Command{
+		Use:   "compare",
+		Short: "Compares the deployed configs of two stages",
+		RunE:  compare,
+	}
+)
+
+func init() {
+	compareCmd.Flags().StringVar(&againstStage, "against", "", "stage to compare with")
+	compareCmd.MarkFlagRequired("against")
+
+	rootCmd.AddCommand(compareCmd)
+}
+
+// stageValues are the deployed configs of a stage by the name relative to
+// the prefix. Shared configs are under shared/
+type stageValues struct {
+	store   store.Store
+	inputs  map[string]store.ConfigInput
+	configs map[string]store.Config
+}
+
+func compare(_ *cobra.Command, _ []string) error {
+	if stage == againstStage {
+		return errors.New("--against must be a different stage than --stage")
+	}
+
+	config, err := loadConfig()
+
+	if err != nil {
+		return errors.Wrap(err, "failed to load config")
+	}
+
+	against, err := c.Load(c.LoadConfigInput{
+		Path:      pathToConfig,
+		Stage:     againstStage,
+		Variables: vars,
+		GetStore:  getStore,
+		GetAwsEnv: getAwsEnv,
+	})
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to load config for stage %s", againstStage)
+	}
+
+	left, err := loadStageValues(config)
+
+	if err != nil {
+		return err
+	}
+
+	right, err := loadStageValues(against)
+
+	if err != nil {
+		return err
+	}
+
+	keys := map[string]bool{}
+	for k := range left.inputs {
+		keys[k] = true
+	}
+	for k := range right.inputs {
+		keys[k] = true
+	}
+
+	var sorted []string
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, '\t', 0)
+
+	fmt.Fprintf(w, "Key\t%s\t%s\tStatus\n", stage, againstStage)
+
+	differences := 0
+
+	for _, k := range sorted {
+		l, lok := left.configs[k]
+		r, rok := right.configs[k]
+		secret := left.inputs[k].Secret || right.inputs[k].Secret
+
+		status := compareStatus(l, lok, r, rok)
+
+		if status != "same" {
+			differences++
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			k,
+			compareCell(l, lok, secret),
+			compareCell(r, rok, secret),
+			status,
+		)
+	}
+
+	fmt.Fprintln(w, "---")
+	w.Flush()
+
+	PrintSummary(Summary{
+		Message: fmt.Sprintf("keys = %d, differences = %d, against = %s", len(sorted), differences, againstStage),
+		Config:  *config,
+	})
+
+	return nil
+}
+
+func loadStageValues(config *c.Config) (*stageValues, error) {
+	st, err := getStore(config.StoreConfig())
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to instantiate store for stage %s", config.Stage)
+	}
+
+	configs, err := st.GetMany(config.All)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get params of stage %s", config.Stage)
+	}
+
+	result := &stageValues{
+		store:   st,
+		inputs:  map[string]store.ConfigInput{},
+		configs: map[string]store.Config{},
+	}
+
+	names := map[string]string{}
+
+	for _, input := range config.All {
+		k := relativeName(config, input.Name)
+		names[input.Name] = k
+		result.inputs[k] = input
+	}
+
+	for _, cfg := range configs {
+		result.configs[names[*cfg.Name]] = cfg
+	}
+
+	return result, nil
+}
+
+// relativeName returns the name without the stage specific prefix so that
+// the same key can be matched across stages
+func relativeName(config *c.Config, name string) string {
+	if strings.HasPrefix(name, config.Prefix) {
+		return strings.TrimPrefix(name, config.Prefix)
+	}
+
+	return fmt.Sprintf("shared/%s", name[strings.LastIndex(name, "/")+1:])
+}
+
+// compareStatus compares the values only. Versions are shown but not compared,
+// they count the writes of each stage or are ids with secrets-manager
+func compareStatus(l store.Config, lok bool, r store.Config, rok bool) string {
+	switch {
+	case !lok && !rok:
+		return "missing in both"
+	case !lok:
+		return fmt.Sprintf("missing in %s", stage)
+	case !rok:
+		return fmt.Sprintf("missing in %s", againstStage)
+	case *l.Value != *r.Value:
+		return "value differs"
+	default:
+		return "same"
+	}
+}
+
+// compareCell shows the value and version. Secrets are shown as a hash of
+// the value
+func compareCell(cfg store.Config, ok bool, secret bool) string {
+	if !ok {
+		return "-"
+	}
+
+	value := *cfg.Value
+
+	if secret || cfg.Type == "SecureString" {
+		value = fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(value)))[:19]
+	} else if len(value) > 40 {
+		value = value[:37] + "..."
+	}
+
+	value = strings.ReplaceAll(value, "\n", "\\n")
+
+	return fmt.Sprintf("%s (v%s)", value, cfg.Version)
+}
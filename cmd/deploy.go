@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 
+	c "github.com/adikari/safebox/v2/config"
 	"github.com/adikari/safebox/v2/store"
 	"github.com/manifoldco/promptui"
 	"github.com/pkg/errors"
@@ -12,6 +13,7 @@ import (
 var (
 	removeOrphans bool
 	prompt        string
+	dryRun        bool
 
 	deployCmd = &cobra.Command{
 		Use:   "deploy",
@@ -24,6 +26,7 @@ func init() {
 	rootCmd.AddCommand(deployCmd)
 	deployCmd.Flags().BoolVarP(&removeOrphans, "remove-orphans", "r", false, "remove orphan configurations")
 	deployCmd.Flags().StringVarP(&prompt, "prompt", "p", "", "prompt for configurations (missing or all)")
+	deployCmd.Flags().BoolVar(&dryRun, "dry-run", false, "show changes without deploying")
 }
 
 func deploy(_ *cobra.Command, _ []string) error {
@@ -37,11 +40,15 @@ func deploy(_ *cobra.Command, _ []string) error {
 		return errors.Wrap(err, "failed to load config")
 	}
 
-	st, err := store.GetStore(store.StoreConfig{
-		Provider: config.Provider,
-		Region:   config.Region,
-		FilePath: config.Filepath,
-	})
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	if dryRun {
+		return printDiff(config)
+	}
+
+	st, err := getStore(config.StoreConfig())
 
 	if err != nil {
 		return errors.Wrap(err, "failed to instantiate store")
@@ -53,19 +60,28 @@ func deploy(_ *cobra.Command, _ []string) error {
 		return errors.Wrap(err, "failed to read existing params")
 	}
 
-	missing := getMissing(config.Secrets, all)
+	generated, missing, err := generateMissing(config, getMissing(config.Secrets, all), all)
 
-	if len(missing) > 0 && prompt == "" {
-		return errors.New("config values missing. run deploy with \"--prompt\" flag")
+	if err != nil {
+		return err
 	}
 
-	configsToDeploy := []store.ConfigInput{}
+	configsToDeploy := generated
+
+	if hasRequired(config, missing) && prompt == "" {
+		return errors.New("config values missing. run deploy with \"--prompt\" flag")
+	}
 
 	// prompt for missing secrets
 	if prompt == "missing" {
 		for _, c := range missing {
 			if c.Value == "" {
-				configsToDeploy = append(configsToDeploy, promptConfig(c))
+				userInput := promptConfig(c, config.Schema(c))
+
+				// optional secrets left empty are not deployed
+				if userInput.Value != "" {
+					configsToDeploy = append(configsToDeploy, userInput)
+				}
 			}
 		}
 	}
@@ -73,6 +89,11 @@ func deploy(_ *cobra.Command, _ []string) error {
 	// prompt for all secrets and provide existing value as default
 	if prompt == "all" {
 		for _, c := range config.Secrets {
+			// generated secrets are not prompted for
+			if contains(generated, c.Name) {
+				continue
+			}
+
 			var existingValue string
 			for _, a := range all {
 				if c.Name == *a.Name {
@@ -81,9 +102,9 @@ func deploy(_ *cobra.Command, _ []string) error {
 				}
 			}
 
-			userInput := promptConfig(c)
+			userInput := promptConfig(c, config.Schema(c))
 
-			if userInput.Value != existingValue {
+			if userInput.Value != "" && userInput.Value != existingValue {
 				configsToDeploy = append(configsToDeploy, userInput)
 			}
 		}
@@ -114,8 +135,20 @@ func deploy(_ *cobra.Command, _ []string) error {
 		return errors.Wrap(err, "failed to write params")
 	}
 
+	retagged, err := retag(st, config, all, configsToDeploy)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to update tags")
+	}
+
+	replicated, err := replicate(st, config, all, configsToDeploy)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to replicate params")
+	}
+
 	if removeOrphans {
-		orphans, err := doRemoveOrphans(st, config.Prefix, config.All)
+		orphans, err := doRemoveOrphans(st, config.Prefix, withRefTargets(config))
 		if err != nil {
 			fmt.Printf("%s\n", errors.Wrap(err, "Error: failed to remove orphan"))
 		}
@@ -126,9 +159,11 @@ func deploy(_ *cobra.Command, _ []string) error {
 	if len(config.Generate) > 0 {
 		for _, t := range config.Generate {
 			err := exportToFile(ExportParams{
-				config: config,
-				format: t.Type,
-				output: t.Path,
+				config:    config,
+				format:    t.Type,
+				output:    t.Path,
+				name:      t.Name,
+				namespace: t.Namespace,
 			})
 
 			if err != nil {
@@ -141,48 +176,176 @@ func deploy(_ *cobra.Command, _ []string) error {
 		}
 	}
 
+	msg := fmt.Sprintf("%s = %d", "new configs", len(configsToDeploy))
+
+	if len(config.Regions) > 1 {
+		msg += fmt.Sprintf(", replicated = %d", replicated)
+	}
+
+	if retagged > 0 {
+		msg += fmt.Sprintf(", retagged = %d", retagged)
+	}
+
 	PrintSummary(Summary{
-		Message: fmt.Sprintf("%s = %d", "new configs", len(configsToDeploy)),
+		Message: msg,
 		Config:  *config,
 	})
 
 	return nil
 }
 
-func doRemoveOrphans(st store.Store, prefix string, all []store.ConfigInput) ([]store.ConfigInput, error) {
-	var orphans []store.ConfigInput
-	params, err := st.GetByPath(prefix)
+// retag replaces the tags of the existing configs that are not deployed when
+// their tags in the config file changed. Returns the number of configs retagged
+func retag(st store.Store, config *c.Config, existing []store.Config, deployed []store.ConfigInput) (int, error) {
+	ts, ok := st.(store.TagStore)
+
+	if !ok {
+		return 0, nil
+	}
+
+	found := map[string]bool{}
+	for _, e := range existing {
+		found[*e.Name] = true
+	}
+
+	var candidates []store.ConfigInput
+	for _, input := range config.All {
+		if found[input.Name] && !contains(deployed, input.Name) {
+			candidates = append(candidates, input)
+		}
+	}
+
+	if len(candidates) <= 0 {
+		return 0, nil
+	}
+
+	tags, err := ts.GetTags(candidates)
 
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	for _, param := range params {
-		exists := false
+	var changed []store.ConfigInput
+	for _, input := range candidates {
+		if store.TagsDiffer(tags[input.Name], input.Tags) {
+			changed = append(changed, input)
+		}
+	}
 
-		for _, config := range all {
-			if config.Name == *param.Name {
-				exists = true
-				break
+	if len(changed) <= 0 {
+		return 0, nil
+	}
+
+	return len(changed), ts.SetTags(changed)
+}
+
+// doRemoveOrphans deletes the orphans of every region
+func doRemoveOrphans(st store.Store, prefix string, all []store.ConfigInput) ([]store.ConfigInput, error) {
+	var orphans []store.ConfigInput
+
+	for _, r := range regionStores(st) {
+		var regionOrphans []store.ConfigInput
+		params, err := r.Store.GetByPath(prefix)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, param := range params {
+			exists := false
+
+			for _, config := range all {
+				if config.Name == *param.Name {
+					exists = true
+					break
+				}
+			}
+
+			if !exists {
+				regionOrphans = append(regionOrphans, store.ConfigInput{Name: *param.Name})
 			}
 		}
 
-		if !exists {
-			orphans = append(orphans, store.ConfigInput{Name: *param.Name})
+		if err = r.Store.DeleteMany(regionOrphans); err != nil {
+			return nil, err
 		}
-	}
 
-	if err = st.DeleteMany(orphans); err != nil {
-		return nil, err
+		orphans = append(orphans, regionOrphans...)
 	}
 
 	return orphans, nil
 }
 
-func promptConfig(config store.ConfigInput) store.ConfigInput {
+// replicate writes the configs that are missing or out of date in a region
+// when configs are replicated. Secrets get the value found in any region.
+// Returns the number of configs written
+func replicate(st store.Store, config *c.Config, existing []store.Config, deployed []store.ConfigInput) (int, error) {
+	regions := regionStores(st)
+
+	if len(regions) <= 1 {
+		return 0, nil
+	}
+
+	values := map[string]string{}
+	for _, e := range existing {
+		values[*e.Name] = *e.Value
+	}
+
+	var desired []store.ConfigInput
+
+	for _, input := range config.All {
+		if contains(deployed, input.Name) {
+			continue
+		}
+
+		if input.Secret {
+			value, ok := values[input.Name]
+
+			if !ok {
+				continue
+			}
+
+			input.Value = value
+		}
+
+		desired = append(desired, input)
+	}
+
+	count := 0
+
+	for _, r := range regions {
+		found, err := r.Store.GetMany(desired)
+
+		if err != nil {
+			return count, errors.Wrap(err, r.Region)
+		}
+
+		current := map[string]string{}
+		for _, f := range found {
+			current[*f.Name] = *f.Value
+		}
+
+		var stale []store.ConfigInput
+		for _, input := range desired {
+			if value, ok := current[input.Name]; !ok || value != input.Value {
+				stale = append(stale, input)
+			}
+		}
+
+		if err := r.Store.PutMany(stale); err != nil {
+			return count, errors.Wrap(err, r.Region)
+		}
+
+		count += len(stale)
+	}
+
+	return count, nil
+}
+
+func promptConfig(config store.ConfigInput, schema c.Schema) store.ConfigInput {
 	validate := func(input string) error {
-		if len(input) < 1 {
-			return fmt.Errorf("%s must not be empty", config.Name)
+		if err := schema.Validate(input); err != nil {
+			return fmt.Errorf("%s: %s", config.Key(), err)
 		}
 		return nil
 	}
@@ -200,6 +363,91 @@ func promptConfig(config store.ConfigInput) store.ConfigInput {
 	return config
 }
 
+// generateMissing generates the values of the missing secrets that declare a
+// generator. The password of a bcrypt secret is written with the hash. When
+// only the hash is missing the existing password is hashed, and when only the
+// password is missing both are generated again. Returns the generated secrets
+// and the secrets still missing
+func generateMissing(config *c.Config, missing []store.ConfigInput, existing []store.Config) ([]store.ConfigInput, []store.ConfigInput, error) {
+	toGenerate := map[string]bool{}
+	for _, input := range missing {
+		if hash, ok := config.HashedBy(input.Name); ok {
+			toGenerate[hash] = true
+		} else if _, ok := config.Generator(input.Name); ok {
+			toGenerate[input.Name] = true
+		}
+	}
+
+	generated := []store.ConfigInput{}
+
+	for _, input := range config.Secrets {
+		if !toGenerate[input.Name] {
+			continue
+		}
+
+		g, _ := config.Generator(input.Name)
+		password, hashed := config.PasswordSecret(input.Name)
+
+		var err error
+
+		if value, ok := existingValue(existing, password.Name); hashed && ok && !contains(missing, password.Name) {
+			input.Value, err = g.Hash(value)
+		} else {
+			input.Value, password.Value, err = g.Generate()
+		}
+
+		if err != nil {
+			return nil, nil, errors.Wrap(err, fmt.Sprintf("failed to generate %s", input.Name))
+		}
+
+		generated = append(generated, input)
+
+		if hashed && password.Value != "" {
+			generated = append(generated, password)
+		}
+	}
+
+	var remaining []store.ConfigInput
+	for _, input := range missing {
+		if !contains(generated, input.Name) {
+			remaining = append(remaining, input)
+		}
+	}
+
+	return generated, remaining, nil
+}
+
+func existingValue(existing []store.Config, name string) (string, bool) {
+	for _, e := range existing {
+		if *e.Name == name {
+			return *e.Value, true
+		}
+	}
+
+	return "", false
+}
+
+func contains(configs []store.ConfigInput, name string) bool {
+	for _, c := range configs {
+		if c.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasRequired returns true if any of the configs is required
+func hasRequired(config *c.Config, configs []store.ConfigInput) bool {
+	for _, input := range configs {
+		if config.Schema(input).Required {
+			return true
+		}
+	}
+
+	return false
+}
+
 func getMissing(a []store.ConfigInput, b []store.Config) []store.ConfigInput {
 	mb := make(map[string]struct{}, len(b))
 
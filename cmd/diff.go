@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	c "github.com/adikari/safebox/v2/config"
+	"github.com/adikari/safebox/v2/store"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	showUnchanged bool
+
+	diffCmd = &cobra.Command{
+		Use:   "diff",
+		Short: "Shows changes that deploy would make",
+		RunE:  diffE,
+	}
+)
+
+func init() {
+	diffCmd.Flags().BoolVarP(&showUnchanged, "unchanged", "u", false, "also print unchanged configs")
+	diffCmd.Flags().BoolVarP(&removeOrphans, "remove-orphans", "r", false, "also count orphan configurations as drift")
+
+	rootCmd.AddCommand(diffCmd)
+}
+
+type Changes struct {
+	Added     []store.ConfigInput
+	Changed   []ChangedConfig
+	Unchanged []store.ConfigInput
+	Orphans   []store.Config
+}
+
+type ChangedConfig struct {
+	Input    store.ConfigInput
+	Existing store.Config
+}
+
+// HasDrift reports whether deploy would change the store. Orphans are only
+// removed by deploy with --remove-orphans
+func (c Changes) HasDrift(orphans bool) bool {
+	return len(c.Added) > 0 || len(c.Changed) > 0 || (orphans && len(c.Orphans) > 0)
+}
+
+func diffE(_ *cobra.Command, _ []string) error {
+	config, err := loadConfig()
+
+	if err != nil {
+		return errors.Wrap(err, "failed to load config")
+	}
+
+	return printDiff(config)
+}
+
+func printDiff(config *c.Config) error {
+	st, err := getStore(config.StoreConfig())
+
+	if err != nil {
+		return errors.Wrap(err, "failed to instantiate store")
+	}
+
+	regions := regionStores(st)
+	drift := false
+
+	// drift is reported per region when configs are replicated
+	for _, r := range regions {
+		changes, err := getChanges(r.Store, config)
+
+		if err != nil {
+			return err
+		}
+
+		if len(regions) > 1 {
+			fmt.Printf("%s:\n", r.Region)
+		}
+
+		writeChanges(changes, os.Stdout)
+
+		msg := fmt.Sprintf("added = %d, changed = %d, unchanged = %d, orphans = %d",
+			len(changes.Added), len(changes.Changed), len(changes.Unchanged), len(changes.Orphans))
+
+		if len(regions) > 1 {
+			msg = fmt.Sprintf("%s: %s", r.Region, msg)
+		}
+
+		PrintSummary(Summary{
+			Message: msg,
+			Config:  *config,
+		})
+
+		drift = drift || changes.HasDrift(removeOrphans)
+	}
+
+	if drift {
+		return errors.New("configs are out of sync with the store")
+	}
+
+	return nil
+}
+
+func getChanges(st store.Store, config *c.Config) (Changes, error) {
+	changes := Changes{}
+
+	all, err := st.GetMany(config.All)
+
+	if err != nil {
+		return changes, errors.Wrap(err, "failed to read existing params")
+	}
+
+	existing := map[string]store.Config{}
+	for _, a := range all {
+		existing[*a.Name] = a
+	}
+
+	for _, input := range config.All {
+		found, ok := existing[input.Name]
+
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, input)
+		case !input.Secret && input.Value != *found.Value:
+			changes.Changed = append(changes.Changed, ChangedConfig{Input: input, Existing: found})
+		default:
+			changes.Unchanged = append(changes.Unchanged, input)
+		}
+	}
+
+	params, err := st.GetByPath(config.Prefix)
+
+	if err != nil {
+		return changes, errors.Wrap(err, "failed to read params by path")
+	}
+
+	declared := withRefTargets(config)
+
+	for _, param := range params {
+		exists := false
+
+		for _, input := range declared {
+			if input.Name == *param.Name {
+				exists = true
+				break
+			}
+		}
+
+		if !exists {
+			changes.Orphans = append(changes.Orphans, param)
+		}
+	}
+
+	return changes, nil
+}
+
+func writeChanges(changes Changes, w io.Writer) {
+	for _, input := range changes.Added {
+		fmt.Fprintf(w, "+ %s (%s)\n", input.Name, configKind(input.Secret))
+	}
+
+	for _, change := range changes.Changed {
+		fmt.Fprintf(w, "~ %s (%s)\n", change.Input.Name, configKind(change.Input.Secret))
+		writeUnifiedDiff(w, *change.Existing.Value, change.Input.Value, "    ")
+	}
+
+	for _, orphan := range changes.Orphans {
+		fmt.Fprintf(w, "- %s (orphan)\n", *orphan.Name)
+	}
+
+	if showUnchanged {
+		for _, input := range changes.Unchanged {
+			fmt.Fprintf(w, "  %s (%s)\n", input.Name, configKind(input.Secret))
+		}
+	}
+}
+
+func configKind(secret bool) string {
+	if secret {
+		return "secret"
+	}
+
+	return "config"
+}
+
+// writeUnifiedDiff writes a single hunk unified diff between the stored and
+// configured value
+func writeUnifiedDiff(w io.Writer, from string, to string, indent string) {
+	a := strings.Split(from, "\n")
+	b := strings.Split(to, "\n")
+
+	fmt.Fprintf(w, "%s--- store\n", indent)
+	fmt.Fprintf(w, "%s+++ config\n", indent)
+	fmt.Fprintf(w, "%s@@ -1,%d +1,%d @@\n", indent, len(a), len(b))
+
+	for _, line := range diffLines(a, b) {
+		fmt.Fprintf(w, "%s%s\n", indent, line)
+	}
+}
+
+// diffLines returns the lines of a and b prefixed with "-", "+" or " " based on
+// their longest common subsequence
+func diffLines(a []string, b []string) []string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var result []string
+	i, j := 0, 0
+
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			result = append(result, " "+a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			result = append(result, "-"+a[i])
+			i++
+		default:
+			result = append(result, "+"+b[j])
+			j++
+		}
+	}
+
+	for ; i < len(a); i++ {
+		result = append(result, "-"+a[i])
+	}
+
+	for ; j < len(b); j++ {
+		result = append(result, "+"+b[j])
+	}
+
+	return result
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/adikari/safebox/v2/store"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	keysToInject []string
+
+	execCmd = &cobra.Command{
+		Use:   "exec -- <command> [args...]",
+		Short: "Executes a command with configs injected as environment variables",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  execE,
+	}
+)
+
+func init() {
+	execCmd.Flags().StringSliceVarP(&keysToInject, "key", "k", []string{}, "only inject specified config (default is inject all)")
+
+	rootCmd.AddCommand(execCmd)
+}
+
+func execE(cmd *cobra.Command, args []string) error {
+	config, err := loadConfig()
+
+	if err != nil {
+		return errors.Wrap(err, "failed to load config")
+	}
+
+	st, err := getStore(config.StoreConfig())
+
+	if err != nil {
+		return errors.Wrap(err, "failed to instantiate store")
+	}
+
+	all := append([]store.ConfigInput{}, config.All...)
+	for _, ref := range config.Refs {
+		all = append(all, ref.Input())
+	}
+
+	toInject, err := configsToExport(all, keysToInject)
+
+	if err != nil {
+		return err
+	}
+
+	configs, err := getWithRefs(st, config, toInject)
+
+	if err != nil {
+		return err
+	}
+
+	env := os.Environ()
+	for _, c := range configs {
+		env = append(env, fmt.Sprintf("%s=%s", envName(c.Key()), *c.Value))
+	}
+
+	child := exec.Command(args[0], args[1:]...)
+	child.Env = env
+	child.Stdin = os.Stdin
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
+	defer signal.Stop(signals)
+
+	if err := child.Start(); err != nil {
+		return errors.Wrapf(err, "failed to run %s", args[0])
+	}
+
+	go func() {
+		for sig := range signals {
+			child.Process.Signal(sig)
+		}
+	}()
+
+	if err := child.Wait(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			code := exitErr.ExitCode()
+
+			// terminated by a signal. shells report 128 + signal
+			if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+				code = 128 + int(status.Signal())
+			}
+
+			cmd.SilenceErrors = true
+			return &ExitError{Code: code}
+		}
+
+		return errors.Wrapf(err, "failed to run %s", args[0])
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bufio"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -23,6 +24,8 @@ var (
 	exportFormat string
 	outputFile   string
 	keysToExport []string
+	k8sName      string
+	k8sNamespace string
 
 	exportCmd = &cobra.Command{
 		Use:   "export",
@@ -32,9 +35,11 @@ var (
 )
 
 func init() {
-	exportCmd.Flags().StringVarP(&exportFormat, "format", "f", "json", "output format (json, yaml, dotenv, types-node)")
+	exportCmd.Flags().StringVarP(&exportFormat, "format", "f", "json", "output format (json, yaml, dotenv, types-node, k8s-secret, k8s-configmap)")
 	exportCmd.Flags().StringVarP(&outputFile, "output-file", "o", "", "output file (default is standard output)")
 	exportCmd.Flags().StringSliceVarP(&keysToExport, "key", "k", []string{}, "only export specified config (default is export all)")
+	exportCmd.Flags().StringVar(&k8sName, "k8s-name", "", "name of the k8s manifest (default is service name)")
+	exportCmd.Flags().StringVar(&k8sNamespace, "k8s-namespace", "", "namespace of the k8s manifest")
 	exportCmd.MarkFlagFilename("output-file")
 
 	rootCmd.AddCommand(exportCmd)
@@ -52,6 +57,8 @@ func export(_ *cobra.Command, _ []string) error {
 		keysToExport: keysToExport,
 		format:       exportFormat,
 		output:       outputFile,
+		name:         k8sName,
+		namespace:    k8sNamespace,
 	})
 }
 
@@ -60,29 +67,47 @@ type ExportParams struct {
 	keysToExport []string
 	format       string
 	output       string
+	name         string
+	namespace    string
 }
 
 func exportToFile(p ExportParams) error {
-	store, err := store.GetStore(store.StoreConfig{
-		Provider: p.config.Provider,
-		Region:   p.config.Region,
-		FilePath: p.config.Filepath,
-	})
+	store, err := getStore(p.config.StoreConfig())
 
 	if err != nil {
 		return errors.Wrap(err, "failed to instantiate store")
 	}
 
-	toExport, err := configsToExport(p.config.All, p.keysToExport)
+	format := strings.ToLower(p.format)
+
+	all := p.config.All
+	switch format {
+	case "k8s-secret":
+		all = p.config.Secrets
+	case "k8s-configmap":
+		all = p.config.Configs
+	}
+
+	// refs are exported with the configs of the same kind
+	all = append(all[:0:0], all...)
+	for _, ref := range p.config.Refs {
+		if (format == "k8s-secret" && !ref.Secret) || (format == "k8s-configmap" && ref.Secret) {
+			continue
+		}
+
+		all = append(all, ref.Input())
+	}
+
+	toExport, err := configsToExport(all, p.keysToExport)
 
 	if err != nil {
 		return err
 	}
 
-	configs, err := store.GetMany(toExport)
+	configs, err := getWithRefs(store, p.config, toExport)
 
 	if err != nil {
-		return errors.Wrap(err, "failed to get params")
+		return err
 	}
 
 	file := os.Stdout
@@ -108,11 +133,22 @@ func exportToFile(p ExportParams) error {
 	defer w.Flush()
 
 	params := map[string]string{}
+	types := map[string]string{}
 	for _, c := range configs {
 		params[c.Key()] = *c.Value
+		types[c.Key()] = p.config.Schemas[*c.Name].TypescriptType()
 	}
 
-	switch strings.ToLower(p.format) {
+	manifest := k8sMetadata{
+		Name:      p.name,
+		Namespace: p.namespace,
+	}
+
+	if manifest.Name == "" {
+		manifest.Name = k8sResourceName(p.config.Service)
+	}
+
+	switch format {
 	case "json":
 		err = exportAsJson(params, w)
 	case "yaml":
@@ -120,9 +156,13 @@ func exportToFile(p ExportParams) error {
 	case "dotenv":
 		err = exportAsEnvFile(params, w)
 	case "types-node":
-		err = exportAsTypesNode(params, w)
+		err = exportAsTypesNode(params, types, w)
+	case "k8s-secret":
+		err = exportAsK8sSecret(params, manifest, w)
+	case "k8s-configmap":
+		err = exportAsK8sConfigMap(params, manifest, w)
 	default:
-		err = errors.Errorf("unsupported export format: %s", exportFormat)
+		err = errors.Errorf("unsupported export format: %s", p.format)
 	}
 
 	if err != nil {
@@ -132,14 +172,16 @@ func exportToFile(p ExportParams) error {
 	return nil
 }
 
-func exportAsTypesNode(params map[string]string, w io.Writer) error {
+// exportAsTypesNode writes the declaration of process.env with the type of
+// each value from the schema
+func exportAsTypesNode(params map[string]string, types map[string]string, w io.Writer) error {
 	w.Write([]byte(fmt.Sprintf("declare global {\n")))
 	w.Write([]byte(fmt.Sprintf("  namespace NodeJS {\n")))
 	w.Write([]byte(fmt.Sprintf("    interface ProcessEnv {\n")))
 
 	for _, k := range sortedKeys(params) {
 		key := strings.ToUpper(k)
-		w.Write([]byte(fmt.Sprintf(`      %s: string;`+"\n", key)))
+		w.Write([]byte(fmt.Sprintf(`      %s: %s;`+"\n", key, types[k])))
 	}
 
 	w.Write([]byte(fmt.Sprintf("    }\n")))
@@ -152,13 +194,15 @@ func exportAsTypesNode(params map[string]string, w io.Writer) error {
 
 func exportAsEnvFile(params map[string]string, w io.Writer) error {
 	for _, k := range sortedKeys(params) {
-		key := strings.ToUpper(k)
-		key = strings.Replace(key, "-", "_", -1)
-		w.Write([]byte(fmt.Sprintf(`%s="%s"`+"\n", key, doubleQuoteEscape(params[k]))))
+		w.Write([]byte(fmt.Sprintf(`%s="%s"`+"\n", envName(k), doubleQuoteEscape(params[k]))))
 	}
 	return nil
 }
 
+func envName(key string) string {
+	return strings.Replace(strings.ToUpper(key), "-", "_", -1)
+}
+
 func exportAsJson(params map[string]string, w io.Writer) error {
 	d, err := json.MarshalIndent(params, "", "  ")
 	if err != nil {
@@ -172,6 +216,59 @@ func exportAsYaml(params map[string]string, w io.Writer) error {
 	return yaml.NewEncoder(w).Encode(params)
 }
 
+type k8sManifest struct {
+	ApiVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   k8sMetadata       `yaml:"metadata"`
+	Type       string            `yaml:"type,omitempty"`
+	Data       map[string]string `yaml:"data"`
+}
+
+type k8sMetadata struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace,omitempty"`
+}
+
+func exportAsK8sSecret(params map[string]string, metadata k8sMetadata, w io.Writer) error {
+	data := map[string]string{}
+	for k, v := range params {
+		data[k] = base64.StdEncoding.EncodeToString([]byte(v))
+	}
+
+	return yaml.NewEncoder(w).Encode(k8sManifest{
+		ApiVersion: "v1",
+		Kind:       "Secret",
+		Metadata:   metadata,
+		Type:       "Opaque",
+		Data:       data,
+	})
+}
+
+func exportAsK8sConfigMap(params map[string]string, metadata k8sMetadata, w io.Writer) error {
+	return yaml.NewEncoder(w).Encode(k8sManifest{
+		ApiVersion: "v1",
+		Kind:       "ConfigMap",
+		Metadata:   metadata,
+		Data:       params,
+	})
+}
+
+// k8sResourceName converts the name to a valid kubernetes resource name
+func k8sResourceName(name string) string {
+	name = strings.ToLower(name)
+
+	var result strings.Builder
+	for _, c := range name {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '.' {
+			result.WriteRune(c)
+		} else {
+			result.WriteRune('-')
+		}
+	}
+
+	return strings.Trim(result.String(), "-.")
+}
+
 func sortedKeys(params map[string]string) []string {
 	keys := make([]string, len(params))
 	i := 0
@@ -32,11 +32,7 @@ func getE(_ *cobra.Command, _ []string) error {
 		return errors.Wrap(err, "failed to load config")
 	}
 
-	st, err := store.GetStore(store.StoreConfig{
-		Provider: config.Provider,
-		Region:   config.Region,
-		FilePath: config.Filepath,
-	})
+	st, err := getStore(config.StoreConfig())
 
 	if err != nil {
 		return errors.Wrap(err, "failed to instantiate store")
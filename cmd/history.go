@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyParam string
+
+	historyCmd = &cobra.Command{
+		Use:   "history",
+		Short: "Lists all versions of a parameter",
+		RunE:  history,
+	}
+)
+
+func init() {
+	historyCmd.Flags().StringVarP(&historyParam, "param", "p", "", "parameter to list versions of")
+	historyCmd.MarkFlagRequired("param")
+
+	rootCmd.AddCommand(historyCmd)
+}
+
+func history(_ *cobra.Command, _ []string) error {
+	config, err := loadConfig()
+
+	if err != nil {
+		return errors.Wrap(err, "failed to load config")
+	}
+
+	st, err := getStore(config.StoreConfig())
+
+	if err != nil {
+		return errors.Wrap(err, "failed to instantiate store")
+	}
+
+	input := findConfig(config, historyParam)
+	versions, err := st.GetHistory(input)
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to get history of %s", input.Name)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, '\t', 0)
+
+	fmt.Fprint(w, "Version\tValue\tType\tModified\tModifiedBy")
+	fmt.Fprintln(w, "")
+
+	for _, v := range versions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s",
+			v.Version,
+			*v.Value,
+			v.Type,
+			v.Modified.Local().Format(TimeFormat),
+			v.ModifiedBy,
+		)
+
+		fmt.Fprintln(w, "")
+	}
+	fmt.Fprintln(w, "---")
+	w.Flush()
+
+	PrintSummary(Summary{
+		Message: fmt.Sprintf("%s versions = %d", input.Name, len(versions)),
+		Config:  *config,
+	})
+
+	return nil
+}
@@ -1,14 +1,26 @@
 package cmd
 
 import (
-	"log"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
 
+	c "github.com/adikari/safebox/v2/config"
+	"github.com/adikari/safebox/v2/store"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
 )
 
 var (
 	importFormat string
 	inputFile    string
+	secretKeys   []string
 
 	importCmd = &cobra.Command{
 		Use:   "import",
@@ -20,6 +32,7 @@ var (
 func init() {
 	importCmd.Flags().StringVarP(&importFormat, "format", "f", "json", "input format (json, yaml, dotenv)")
 	importCmd.Flags().StringVarP(&inputFile, "input-file", "i", "", "input file")
+	importCmd.Flags().StringSliceVarP(&secretKeys, "secret", "S", []string{}, "import specified keys as secret (keys declared under secret are always secret)")
 	importCmd.MarkFlagRequired("input-file")
 	importCmd.MarkFlagFilename("input-file")
 
@@ -27,6 +40,232 @@ func init() {
 }
 
 func importE(_ *cobra.Command, _ []string) error {
-	log.Fatalf("not implemented")
+	config, err := loadConfig()
+
+	if err != nil {
+		return errors.Wrap(err, "failed to load config")
+	}
+
+	b, err := ioutil.ReadFile(inputFile)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to read input file")
+	}
+
+	var params map[string]string
+
+	switch strings.ToLower(importFormat) {
+	case "json":
+		params, err = importFromJson(b)
+	case "yaml":
+		params, err = importFromYaml(b)
+	case "dotenv":
+		params, err = importFromEnvFile(bytes.NewReader(b))
+	default:
+		err = errors.Errorf("unsupported import format: %s", importFormat)
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "failed to import parameters")
+	}
+
+	st, err := getStore(config.StoreConfig())
+
+	if err != nil {
+		return errors.Wrap(err, "failed to instantiate store")
+	}
+
+	configs := configsToImport(config, params, secretKeys)
+
+	if err := st.PutMany(configs); err != nil {
+		return errors.Wrap(err, "failed to write params")
+	}
+
+	PrintSummary(Summary{
+		Message: fmt.Sprintf("%s = %d", "imported configs", len(configs)),
+		Config:  *config,
+	})
+
 	return nil
 }
+
+// configsToImport maps each imported key to a parameter name. Keys declared in
+// the config file keep their path, secret classification and tags, everything
+// else is placed under the config prefix with the top level tags.
+func configsToImport(config *c.Config, params map[string]string, secrets []string) []store.ConfigInput {
+	result := []store.ConfigInput{}
+
+	for _, key := range sortedKeys(params) {
+		input := store.ConfigInput{
+			Name:  fmt.Sprintf("%s%s", config.Prefix, key),
+			Value: params[key],
+			Tags:  config.Tags,
+		}
+
+		for _, existing := range config.All {
+			if existing.Key() == key || envName(existing.Key()) == key {
+				input.Name = existing.Name
+				input.Secret = existing.Secret
+				input.Description = existing.Description
+				input.Tags = existing.Tags
+				break
+			}
+		}
+
+		for _, s := range secrets {
+			if s == key {
+				input.Secret = true
+				break
+			}
+		}
+
+		result = append(result, input)
+	}
+
+	return result
+}
+
+func importFromJson(b []byte) (map[string]string, error) {
+	raw := map[string]interface{}{}
+
+	// numbers are kept as written instead of float64 to not lose precision
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	return stringifyValues(raw)
+}
+
+func importFromYaml(b []byte) (map[string]string, error) {
+	raw := map[string]interface{}{}
+
+	if err := yaml.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+
+	return stringifyValues(raw)
+}
+
+func importFromEnvFile(r io.Reader) (map[string]string, error) {
+	params := map[string]string{}
+	scanner := bufio.NewScanner(r)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		text = strings.TrimPrefix(text, "export ")
+
+		parts := strings.SplitN(text, "=", 2)
+
+		if len(parts) != 2 {
+			return nil, errors.Errorf("invalid dotenv entry on line %d", line)
+		}
+
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+
+		switch {
+		case len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`):
+			value = doubleQuoteUnescape(value[1 : len(value)-1])
+		case len(value) >= 2 && strings.HasPrefix(value, `'`) && strings.HasSuffix(value, `'`):
+			value = value[1 : len(value)-1]
+		}
+
+		params[key] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return params, nil
+}
+
+func stringifyValues(raw map[string]interface{}) (map[string]string, error) {
+	params := map[string]string{}
+
+	for key, value := range raw {
+		switch v := value.(type) {
+		case string:
+			params[key] = v
+		case nil:
+			params[key] = ""
+		case json.Number:
+			params[key] = v.String()
+		case int:
+			params[key] = strconv.Itoa(v)
+		case int64:
+			params[key] = strconv.FormatInt(v, 10)
+		case uint64:
+			params[key] = strconv.FormatUint(v, 10)
+		case float64:
+			params[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			params[key] = strconv.FormatBool(v)
+		case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+			b, err := json.Marshal(normalizeValue(v))
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to encode value of %s", key)
+			}
+			params[key] = string(b)
+		default:
+			params[key] = fmt.Sprint(v)
+		}
+	}
+
+	return params, nil
+}
+
+// normalizeValue converts yaml maps to json compatible maps
+func normalizeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for key, val := range v {
+			m[fmt.Sprint(key)] = normalizeValue(val)
+		}
+		return m
+	case map[string]interface{}:
+		for key, val := range v {
+			v[key] = normalizeValue(val)
+		}
+		return v
+	case []interface{}:
+		for i, val := range v {
+			v[i] = normalizeValue(val)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func doubleQuoteUnescape(line string) string {
+	var result strings.Builder
+
+	for i := 0; i < len(line); i++ {
+		if line[i] != '\\' || i == len(line)-1 {
+			result.WriteByte(line[i])
+			continue
+		}
+
+		i++
+		switch line[i] {
+		case 'n':
+			result.WriteByte('\n')
+		case 'r':
+			result.WriteByte('\r')
+		default:
+			result.WriteByte(line[i])
+		}
+	}
+
+	return result.String()
+}
@@ -38,17 +38,13 @@ func list(_ *cobra.Command, _ []string) error {
 		return errors.Wrap(err, "failed to load config")
 	}
 
-	store, err := store.GetStore(store.StoreConfig{
-		Provider: config.Provider,
-		Region:   config.Region,
-		FilePath: config.Filepath,
-	})
+	st, err := getStore(config.StoreConfig())
 
 	if err != nil {
 		return errors.Wrap(err, "failed to instantiate store")
 	}
 
-	configs, err := store.GetMany(config.All)
+	configs, err := st.GetMany(config.All)
 
 	if err != nil {
 		return errors.Wrap(err, "failed to list params")
@@ -62,12 +58,21 @@ func list(_ *cobra.Command, _ []string) error {
 		sort.Sort(ByName(configs))
 	}
 
-	printList(configs, config)
+	var keyIds map[string]string
+
+	if kms, ok := st.(store.KmsStore); ok {
+		if keyIds, err = kms.GetKeyIds(config.All); err != nil {
+			return errors.Wrap(err, "failed to get kms keys")
+		}
+	}
+
+	printList(configs, keyIds, config)
 
 	return nil
 }
 
-func printList(configs []store.Config, cfg *config.Config) {
+// printList prints the configs. The kms key column is shown when keyIds is not nil
+func printList(configs []store.Config, keyIds map[string]string, cfg *config.Config) {
 	if len(configs) <= 0 {
 
 		PrintSummary(Summary{
@@ -81,6 +86,9 @@ func printList(configs []store.Config, cfg *config.Config) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, '\t', 0)
 
 	fmt.Fprint(w, "Name\tValue\tType\tVersion\tLastModified")
+	if keyIds != nil {
+		fmt.Fprint(w, "\tKmsKey")
+	}
 	fmt.Fprintln(w, "")
 
 	for _, config := range configs {
@@ -92,6 +100,14 @@ func printList(configs []store.Config, cfg *config.Config) {
 			config.Modified.Local().Format(TimeFormat),
 		)
 
+		if keyIds != nil {
+			keyId, ok := keyIds[*config.Name]
+			if !ok {
+				keyId = "-"
+			}
+			fmt.Fprintf(w, "\t%s", keyId)
+		}
+
 		fmt.Fprintln(w, "")
 	}
 	fmt.Fprintln(w, "---")
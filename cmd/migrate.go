@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	c "github.com/adikari/safebox/v2/config"
+	"github.com/adikari/safebox/v2/store"
+	"github.com/adikari/safebox/v2/util"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	toProvider string
+	toRegion   string
+
+	migrateCmd = &cobra.Command{
+		Use:   "migrate",
+		Short: "Copies all configs to another provider or region",
+		RunE:  migrate,
+	}
+)
+
+func init() {
+	migrateCmd.Flags().StringVar(&toProvider, "to-provider", "", "provider to copy configs to (ssm, secrets-manager, gpg, vault)")
+	migrateCmd.Flags().StringVar(&toRegion, "to-region", "", "region to copy configs to (default is the region in config)")
+	migrateCmd.MarkFlagRequired("to-provider")
+
+	rootCmd.AddCommand(migrateCmd)
+}
+
+func migrate(_ *cobra.Command, _ []string) error {
+	config, err := loadConfig()
+
+	if err != nil {
+		return errors.Wrap(err, "failed to load config")
+	}
+
+	sourceConfig := config.StoreConfig()
+	targetConfig := migrateStoreConfig(sourceConfig)
+
+	if targetConfig.Provider == sourceConfig.Provider &&
+		(!util.IsAwsProvider(targetConfig.Provider) || targetConfig.Region == sourceConfig.Region) {
+		return errors.New("target provider and region are the same as the source")
+	}
+
+	source, err := getStore(sourceConfig)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to instantiate source store")
+	}
+
+	target, err := getStore(targetConfig)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to instantiate target store")
+	}
+
+	inputs, err := configsToMigrate(source, config)
+
+	if err != nil {
+		return err
+	}
+
+	if err := target.PutMany(inputs); err != nil {
+		return errors.Wrap(err, "failed to write params to target store")
+	}
+
+	if err := verifyMigration(target, inputs); err != nil {
+		return err
+	}
+
+	for _, input := range inputs {
+		fmt.Printf("migrated %s (%s)\n", input.Name, configKind(input.Secret))
+	}
+
+	msg := fmt.Sprintf("migrated = %d, to provider = %s", len(inputs), targetConfig.Provider)
+
+	if util.IsAwsProvider(targetConfig.Provider) && targetConfig.Region != "" {
+		msg += fmt.Sprintf(", to region = %s", targetConfig.Region)
+	}
+
+	PrintSummary(Summary{
+		Message: msg,
+		Config:  *config,
+	})
+
+	return nil
+}
+
+// migrateStoreConfig returns the store config of the target. Settings of the
+// target provider come from the config file
+func migrateStoreConfig(source store.StoreConfig) store.StoreConfig {
+	target := source
+	target.Provider = strings.ToLower(toProvider)
+
+	if toRegion != "" {
+		target.Region = toRegion
+		target.Regions = nil
+	}
+
+	// non aws providers have no region. use the default of the aws profile
+	if target.Region == "local" {
+		target.Region = ""
+	}
+
+	return target
+}
+
+// configsToMigrate returns everything under the prefix and the shared configs
+// in the config file. Configs in the config file keep their secret flag,
+// description and tags. Others are secret if they are stored as SecureString
+// and get the top level tags
+func configsToMigrate(st store.Store, config *c.Config) ([]store.ConfigInput, error) {
+	params, err := st.GetByPath(config.Prefix)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read params by path")
+	}
+
+	var shared []store.ConfigInput
+	for _, input := range config.All {
+		if !strings.HasPrefix(input.Name, config.Prefix) {
+			shared = append(shared, input)
+		}
+	}
+
+	sharedParams, err := st.GetMany(shared)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read shared params")
+	}
+
+	params = append(params, sharedParams...)
+
+	declared := map[string]store.ConfigInput{}
+	for _, input := range config.All {
+		declared[input.Name] = input
+	}
+
+	var inputs []store.ConfigInput
+
+	for _, p := range params {
+		input, ok := declared[*p.Name]
+
+		if !ok {
+			input = store.ConfigInput{
+				Name:   *p.Name,
+				Secret: p.Type == "SecureString",
+				Tags:   config.Tags,
+			}
+		}
+
+		input.Value = *p.Value
+		inputs = append(inputs, input)
+	}
+
+	return inputs, nil
+}
+
+// verifyMigration reads the configs back from the target store and checks the
+// values and secret flag match
+func verifyMigration(st store.Store, inputs []store.ConfigInput) error {
+	migrated, err := st.GetMany(inputs)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to read params from target store")
+	}
+
+	found := map[string]store.Config{}
+	for _, m := range migrated {
+		found[*m.Name] = m
+	}
+
+	var problems []string
+
+	for _, input := range inputs {
+		m, ok := found[input.Name]
+
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%s is missing", input.Name))
+		case *m.Value != input.Value:
+			problems = append(problems, fmt.Sprintf("%s has a different value", input.Name))
+		case input.Secret && m.Type != "SecureString":
+			problems = append(problems, fmt.Sprintf("%s is not stored as a secret", input.Name))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.Errorf("verification of migrated params failed:\n%s", strings.Join(problems, "\n"))
+	}
+
+	return nil
+}
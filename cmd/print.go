@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	c "github.com/adikari/safebox/v2/config"
+	"github.com/adikari/safebox/v2/util"
 )
 
 type Summary struct {
@@ -26,10 +28,16 @@ func PrintSummary(s Summary) {
 		msg += fmt.Sprintf(", stage = %s", s.Config.Stage)
 	}
 
-	if s.Config.Region != "" && s.Config.Provider != "gpg" {
+	if len(s.Config.Regions) > 1 && util.IsAwsProvider(s.Config.Provider) {
+		msg += fmt.Sprintf(", regions = %s", strings.Join(s.Config.Regions, ", "))
+	} else if s.Config.Region != "" && util.IsAwsProvider(s.Config.Provider) {
 		msg += fmt.Sprintf(", region = %s", s.Config.Region)
 	}
 
+	if s.Config.Provider == util.VaultProvider && s.Config.Vault.Address != "" {
+		msg += fmt.Sprintf(", address = %s", s.Config.Vault.Address)
+	}
+
 	if s.Config.Provider == "gpg" {
 		msg += fmt.Sprintf(", file = %s", s.Config.Filepath)
 	}
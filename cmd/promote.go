@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	c "github.com/adikari/safebox/v2/config"
+	"github.com/adikari/safebox/v2/store"
+	"github.com/manifoldco/promptui"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	promoteFrom string
+	promoteTo   string
+	promoteKeys []string
+	promoteYes  bool
+
+	promoteCmd = &cobra.Command{
+		Use:   "promote",
+		Short: "Copies secret values from one stage to another",
+		RunE:  promote,
+	}
+)
+
+func init() {
+	promoteCmd.Flags().StringVar(&promoteFrom, "from", "", "stage to copy secrets from")
+	promoteCmd.Flags().StringVar(&promoteTo, "to", "", "stage to copy secrets to")
+	promoteCmd.Flags().StringSliceVarP(&promoteKeys, "key", "k", []string{}, "only promote specified secrets (default is promote all)")
+	promoteCmd.Flags().BoolVarP(&promoteYes, "yes", "y", false, "promote without asking for confirmation")
+	promoteCmd.MarkFlagRequired("from")
+	promoteCmd.MarkFlagRequired("to")
+
+	rootCmd.AddCommand(promoteCmd)
+}
+
+// Promotion is a secret to copy to the target stage
+type Promotion struct {
+	From   string
+	To     store.ConfigInput
+	Exists bool // the secret exists in the target stage
+	Shared bool
+}
+
+func promote(_ *cobra.Command, _ []string) error {
+	if promoteFrom == promoteTo {
+		return errors.New("--from and --to must be different stages")
+	}
+
+	from, err := c.Load(c.LoadConfigInput{Path: pathToConfig, Stage: promoteFrom, Variables: vars, GetStore: getStore, GetAwsEnv: getAwsEnv})
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to load config for stage %s", promoteFrom)
+	}
+
+	to, err := c.Load(c.LoadConfigInput{Path: pathToConfig, Stage: promoteTo, Variables: vars, GetStore: getStore, GetAwsEnv: getAwsEnv})
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to load config for stage %s", promoteTo)
+	}
+
+	source, err := loadStageValues(from)
+
+	if err != nil {
+		return err
+	}
+
+	target, err := loadStageValues(to)
+
+	if err != nil {
+		return err
+	}
+
+	promotions, unchanged, err := getPromotions(source, target)
+
+	if err != nil {
+		return err
+	}
+
+	writePromotions(promotions, os.Stdout)
+
+	if len(promotions) <= 0 {
+		PrintSummary(Summary{
+			Message: fmt.Sprintf("nothing to promote, unchanged = %d", unchanged),
+			Config:  *to,
+		})
+
+		return nil
+	}
+
+	if !promoteYes {
+		confirm := promptui.Prompt{
+			Label:     fmt.Sprintf("Promote %d secrets from %s to %s", len(promotions), promoteFrom, promoteTo),
+			IsConfirm: true,
+		}
+
+		if _, err := confirm.Run(); err != nil {
+			return errors.New("promote cancelled")
+		}
+	}
+
+	var inputs []store.ConfigInput
+	for _, p := range promotions {
+		inputs = append(inputs, p.To)
+	}
+
+	if err := target.store.PutMany(inputs); err != nil {
+		return errors.Wrap(err, "failed to write params")
+	}
+
+	PrintSummary(Summary{
+		Message: fmt.Sprintf("promoted = %d, unchanged = %d, from = %s", len(promotions), unchanged, promoteFrom),
+		Config:  *to,
+	})
+
+	return nil
+}
+
+// getPromotions returns the secrets of the target stage whose value differs
+// from the source stage. Secrets are matched by their name relative to the
+// prefix so shared secrets are copied to the shared path of the target stage
+func getPromotions(source *stageValues, target *stageValues) ([]Promotion, int, error) {
+	var keys []string
+	for k := range target.inputs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	selected := map[string]bool{}
+
+	for _, key := range promoteKeys {
+		k, err := findPromoteKey(source, target, key)
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		selected[k] = true
+	}
+
+	var promotions []Promotion
+	unchanged := 0
+
+	for _, k := range keys {
+		input := target.inputs[k]
+
+		if len(selected) > 0 && !selected[k] {
+			continue
+		}
+
+		if !input.Secret {
+			continue
+		}
+
+		value, ok := source.configs[k]
+
+		// the secret is not deployed to the source stage or the prefix is the
+		// same for both stages
+		if !ok || source.inputs[k].Name == input.Name {
+			continue
+		}
+
+		existing, exists := target.configs[k]
+
+		if exists && *existing.Value == *value.Value {
+			unchanged++
+			continue
+		}
+
+		input.Value = *value.Value
+
+		promotions = append(promotions, Promotion{
+			From:   *value.Name,
+			To:     input,
+			Exists: exists,
+			Shared: strings.HasPrefix(k, "shared/"),
+		})
+	}
+
+	return promotions, unchanged, nil
+}
+
+// findPromoteKey returns the relative name of the key. Only secrets in the
+// config of both stages can be promoted
+func findPromoteKey(source *stageValues, target *stageValues, key string) (string, error) {
+	for k, input := range target.inputs {
+		if k != key && input.Key() != key {
+			continue
+		}
+
+		if !input.Secret {
+			return "", errors.Errorf("%s is a config. its value is set in the config file", key)
+		}
+
+		if _, ok := source.inputs[k]; !ok {
+			return "", errors.Errorf("%s is not in the config of stage %s", key, promoteFrom)
+		}
+
+		if _, ok := source.configs[k]; !ok {
+			return "", errors.Errorf("%s is not deployed to stage %s", key, promoteFrom)
+		}
+
+		return k, nil
+	}
+
+	return "", errors.Errorf("%s is not in the config of stage %s", key, promoteTo)
+}
+
+func writePromotions(promotions []Promotion, w io.Writer) {
+	for _, p := range promotions {
+		change := "~"
+		if !p.Exists {
+			change = "+"
+		}
+
+		note := ""
+		if p.Shared {
+			note = fmt.Sprintf(", shared by all services in %s", promoteTo)
+		}
+
+		fmt.Fprintf(w, "%s %s <- %s (secret%s)\n", change, p.To.Name, p.From, note)
+	}
+}
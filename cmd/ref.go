@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"strings"
+
+	c "github.com/adikari/safebox/v2/config"
+	"github.com/adikari/safebox/v2/store"
+	"github.com/adikari/safebox/v2/util"
+	"github.com/pkg/errors"
+)
+
+// getWithRefs reads the configs from the store. Configs that are refs are
+// read from the param they reference
+func getWithRefs(st store.Store, config *c.Config, inputs []store.ConfigInput) ([]store.Config, error) {
+	var local []store.ConfigInput
+	var refs []c.Ref
+
+	for _, input := range inputs {
+		if ref, ok := config.Ref(input.Name); ok {
+			refs = append(refs, ref)
+		} else {
+			local = append(local, input)
+		}
+	}
+
+	configs, err := st.GetMany(local)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get params")
+	}
+
+	resolved, err := resolveRefs(st, config, refs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(configs, resolved...), nil
+}
+
+// resolveRefs reads the referenced params. The configs are named after the
+// key of the ref
+func resolveRefs(st store.Store, config *c.Config, refs []c.Ref) ([]store.Config, error) {
+	result := []store.Config{}
+
+	for _, ref := range refs {
+		refStore, name, err := getRefStore(st, config, ref.Path)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to resolve ref %s", ref.Path)
+		}
+
+		value, err := refStore.Get(store.ConfigInput{Name: name, Secret: ref.Secret})
+
+		if errors.Is(err, store.ConfigNotFoundError) {
+			return nil, errors.Errorf("ref %s of %s is not found", ref.Path, ref.Name)
+		}
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to resolve ref %s", ref.Path)
+		}
+
+		refName := ref.Name
+		value.Name = &refName
+		result = append(result, *value)
+	}
+
+	return result, nil
+}
+
+// getRefStore returns the store to read the ref from and the name of the
+// param in that store. Arns are read from the service and region of the arn,
+// other paths from the store of the service
+func getRefStore(st store.Store, config *c.Config, path string) (store.Store, string, error) {
+	if !strings.HasPrefix(path, "arn:") {
+		return st, path, nil
+	}
+
+	// arn:partition:service:region:account:resource
+	parts := strings.SplitN(path, ":", 6)
+
+	if len(parts) != 6 {
+		return nil, "", errors.New("invalid arn")
+	}
+
+	cfg := config.StoreConfig()
+	cfg.Region = parts[3]
+	cfg.Regions = nil
+
+	name := path
+
+	switch parts[2] {
+	case "secretsmanager":
+		cfg.Provider = util.SecretsManagerProvider
+	case "ssm":
+		if !strings.HasPrefix(parts[5], "parameter/") {
+			return nil, "", errors.New("arn is not of a ssm parameter")
+		}
+
+		cfg.Provider = util.SsmProvider
+		name = "/" + strings.TrimPrefix(parts[5], "parameter/")
+	default:
+		return nil, "", errors.Errorf("unsupported arn service %s", parts[2])
+	}
+
+	refStore, err := getStore(cfg)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	return refStore, name, nil
+}
+
+// withRefTargets returns the configs in config and the params referenced by
+// path so that deploy never removes a referenced param as an orphan
+func withRefTargets(config *c.Config) []store.ConfigInput {
+	all := append([]store.ConfigInput{}, config.All...)
+
+	for _, ref := range config.Refs {
+		if !strings.HasPrefix(ref.Path, "arn:") {
+			all = append(all, store.ConfigInput{Name: ref.Path, Secret: ref.Secret})
+		}
+	}
+
+	return all
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"fmt"
+
+	c "github.com/adikari/safebox/v2/config"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var renderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "Prints the config file merged with the files it extends",
+	RunE:  render,
+}
+
+func init() {
+	configCmd.AddCommand(renderCmd)
+}
+
+func render(_ *cobra.Command, _ []string) error {
+	out, err := c.Render(pathToConfig)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to render config")
+	}
+
+	fmt.Print(string(out))
+
+	return nil
+}
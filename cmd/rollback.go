@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/adikari/safebox/v2/store"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rollbackParam   string
+	rollbackVersion string
+
+	rollbackCmd = &cobra.Command{
+		Use:   "rollback",
+		Short: "Restores a parameter to the value of an earlier version",
+		RunE:  rollback,
+	}
+)
+
+func init() {
+	rollbackCmd.Flags().StringVarP(&rollbackParam, "param", "p", "", "parameter to rollback")
+	rollbackCmd.Flags().StringVar(&rollbackVersion, "version", "", "version to restore")
+	rollbackCmd.MarkFlagRequired("param")
+	rollbackCmd.MarkFlagRequired("version")
+
+	rootCmd.AddCommand(rollbackCmd)
+}
+
+func rollback(_ *cobra.Command, _ []string) error {
+	config, err := loadConfig()
+
+	if err != nil {
+		return errors.Wrap(err, "failed to load config")
+	}
+
+	st, err := getStore(config.StoreConfig())
+
+	if err != nil {
+		return errors.Wrap(err, "failed to instantiate store")
+	}
+
+	input := findConfig(config, rollbackParam)
+	versions, err := st.GetHistory(input)
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to get history of %s", input.Name)
+	}
+
+	var found *store.Config
+	for i, v := range versions {
+		if v.Version == rollbackVersion {
+			found = &versions[i]
+			break
+		}
+	}
+
+	if found == nil {
+		return errors.Errorf("version %s of %s does not exist", rollbackVersion, input.Name)
+	}
+
+	input.Value = *found.Value
+	input.Secret = input.Secret || found.Type == "SecureString"
+
+	if err := st.PutMany([]store.ConfigInput{input}); err != nil {
+		return errors.Wrap(err, "failed to write param")
+	}
+
+	PrintSummary(Summary{
+		Message: fmt.Sprintf("%s restored to version %s", input.Name, rollbackVersion),
+		Config:  *config,
+	})
+
+	return nil
+}
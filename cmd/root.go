@@ -1,19 +1,29 @@
 package cmd
 
 import (
-	"os"
+	"fmt"
 	"strings"
 
 	c "github.com/adikari/safebox/v2/config"
+	"github.com/adikari/safebox/v2/store"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
 var (
 	stage        string
 	pathToConfig string
+	vars         map[string]string
 	TimeFormat   = "2006-01-02 15:04:05"
 )
 
+// getStore instantiates the store of a config and getAwsEnv looks up the aws
+// variables. Tests replace them to run the commands against fakes
+var (
+	getStore  = store.GetStore
+	getAwsEnv = c.NewAwsEnv
+)
+
 var rootCmd = &cobra.Command{
 	Use:          "safebox",
 	Short:        "SafeBox is a secret manager CLI program",
@@ -29,23 +39,71 @@ func init() {
 
 	rootCmd.PersistentFlags().StringVarP(&pathToConfig, "config", "c", "", "path to safebox configuration file")
 	rootCmd.MarkFlagFilename("config")
+
+	rootCmd.PersistentFlags().StringToStringVar(&vars, "var", map[string]string{}, "variable for interpolation, eg. --var domain=example.com. Overrides vars in the config file")
+}
+
+// ExitError exits with the code, eg. to pass on the exit code of a command run
+// by exec. Commands returning it silence the error message
+type ExitError struct {
+	Code int
 }
 
-func Execute(version string) {
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// Execute runs the command and returns the exit code
+func Execute(version string) int {
 	rootCmd.Version = version
 
-	if cmd, err := rootCmd.ExecuteC(); err != nil {
-		if strings.Contains(err.Error(), "arg(s)") || strings.Contains(err.Error(), "usage") {
-			cmd.Usage()
-		}
+	cmd, err := rootCmd.ExecuteC()
+
+	if err == nil {
+		return 0
+	}
+
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
 
-		os.Exit(1)
+	if strings.Contains(err.Error(), "arg(s)") || strings.Contains(err.Error(), "usage") {
+		cmd.Usage()
 	}
+
+	return 1
 }
 
 func loadConfig() (*c.Config, error) {
 	return c.Load(c.LoadConfigInput{
-		Path:  pathToConfig,
-		Stage: stage,
+		Path:      pathToConfig,
+		Stage:     stage,
+		Variables: vars,
+		GetStore:  getStore,
+		GetAwsEnv: getAwsEnv,
 	})
 }
+
+// findConfig returns the config with the given key from the config file. Keys
+// that are not in the config file are looked up under the prefix and get the
+// top level tags
+func findConfig(config *c.Config, key string) store.ConfigInput {
+	for _, input := range config.All {
+		if input.Key() == key {
+			return input
+		}
+	}
+
+	return store.ConfigInput{Name: fmt.Sprintf("%s%s", config.Prefix, key), Tags: config.Tags}
+}
+
+// regionStores returns the store of each region the configs are replicated
+// to. A store of a single region is returned without the region
+func regionStores(st store.Store) []store.RegionStore {
+	if mr, ok := st.(*store.MultiRegionStore); ok {
+		return mr.Regions()
+	}
+
+	return []store.RegionStore{{Store: st}}
+}
@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	c "github.com/adikari/safebox/v2/config"
+	"github.com/adikari/safebox/v2/store"
+	"github.com/adikari/safebox/v2/util"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// RotatedAtTag is the tag with the time a secret was last rotated. Only the
+// aws providers store tags
+const RotatedAtTag = "safebox:rotated-at"
+
+var (
+	rotateParams    []string
+	rotateOlderThan string
+	rotatePostHook  string
+
+	rotateCmd = &cobra.Command{
+		Use:   "rotate",
+		Short: "Regenerates secrets with their configured generator",
+		RunE:  rotate,
+	}
+)
+
+func init() {
+	rotateCmd.Flags().StringSliceVarP(&rotateParams, "param", "p", []string{}, "secret to rotate")
+	rotateCmd.Flags().StringVar(&rotateOlderThan, "all-older-than", "", "rotate all secrets last modified before the given age. eg. 90d, 12h")
+	rotateCmd.Flags().StringVar(&rotatePostHook, "post-hook", "", "command to run after rotating (default is rotation.post-hook in config)")
+
+	rootCmd.AddCommand(rotateCmd)
+}
+
+func rotate(_ *cobra.Command, _ []string) error {
+	if (len(rotateParams) > 0) == (rotateOlderThan != "") {
+		return errors.New("specify either --param or --all-older-than")
+	}
+
+	config, err := loadConfig()
+
+	if err != nil {
+		return errors.Wrap(err, "failed to load config")
+	}
+
+	st, err := getStore(config.StoreConfig())
+
+	if err != nil {
+		return errors.Wrap(err, "failed to instantiate store")
+	}
+
+	var toRotate []store.ConfigInput
+
+	if len(rotateParams) > 0 {
+		for _, key := range rotateParams {
+			input := findConfig(config, key)
+
+			// a password is rotated with its hash
+			if hash, ok := config.HashedBy(input.Name); ok {
+				for _, s := range config.Secrets {
+					if s.Name == hash {
+						input = s
+					}
+				}
+			}
+
+			if _, ok := config.Generator(input.Name); !ok {
+				return errors.Errorf("%s does not have a generator", input.Name)
+			}
+
+			if !contains(toRotate, input.Name) {
+				toRotate = append(toRotate, input)
+			}
+		}
+	} else {
+		toRotate, err = secretsOlderThan(st, config, rotateOlderThan)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	rotatedAt := time.Now().UTC()
+	var rotated []store.ConfigInput
+
+	for _, input := range toRotate {
+		g, _ := config.Generator(input.Name)
+		value, password, err := g.Generate()
+
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("failed to generate %s", input.Name))
+		}
+
+		input.Value = value
+		rotated = append(rotated, rotatedInput(input, rotatedAt))
+
+		if p, ok := config.PasswordSecret(input.Name); ok {
+			p.Value = password
+			rotated = append(rotated, rotatedInput(p, rotatedAt))
+		}
+	}
+
+	toRotate = rotated
+
+	if err := st.PutMany(toRotate); err != nil {
+		return errors.Wrap(err, "failed to write rotated secrets")
+	}
+
+	for _, input := range toRotate {
+		fmt.Printf("rotated %s\n", input.Name)
+	}
+
+	hook := rotatePostHook
+	if hook == "" {
+		hook = config.Rotation.PostHook
+	}
+
+	if hook != "" && len(toRotate) > 0 {
+		if err := runPostHook(hook, config, toRotate); err != nil {
+			return errors.Wrap(err, "secrets were rotated but the post-hook failed")
+		}
+	}
+
+	PrintSummary(Summary{
+		Message: fmt.Sprintf("rotated secrets = %d, rotated at = %s", len(toRotate), rotatedAt.Local().Format(TimeFormat)),
+		Config:  *config,
+	})
+
+	return nil
+}
+
+// rotatedInput returns the secret with the rotation time added to its tags
+func rotatedInput(input store.ConfigInput, rotatedAt time.Time) store.ConfigInput {
+	tags := map[string]string{}
+	for k, v := range input.Tags {
+		tags[k] = v
+	}
+	tags[RotatedAtTag] = rotatedAt.Format(time.RFC3339)
+
+	input.Secret = true
+	input.Tags = tags
+
+	return input
+}
+
+// secretsOlderThan returns the secrets with a generator that were last
+// modified before the age. Missing secrets are left for deploy to generate
+func secretsOlderThan(st store.Store, config *c.Config, age string) ([]store.ConfigInput, error) {
+	d, err := util.ParseAge(age)
+
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := st.GetMany(config.Secrets)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read existing secrets")
+	}
+
+	modified := map[string]time.Time{}
+	for _, e := range existing {
+		modified[*e.Name] = e.Modified
+	}
+
+	cutoff := time.Now().Add(-d)
+	var result []store.ConfigInput
+
+	for _, input := range config.Secrets {
+		m, ok := modified[input.Name]
+
+		if !ok || !m.Before(cutoff) {
+			continue
+		}
+
+		// passwords are rotated with their hash
+		if _, ok := config.HashedBy(input.Name); ok {
+			continue
+		}
+
+		if _, ok := config.Generator(input.Name); !ok {
+			fmt.Printf("skipping %s: no generator, last modified %s\n", input.Name, m.Local().Format(TimeFormat))
+			continue
+		}
+
+		result = append(result, input)
+	}
+
+	return result, nil
+}
+
+// runPostHook runs the hook in a shell with the keys of the rotated secrets in
+// SAFEBOX_ROTATED. Values are not passed to the hook
+func runPostHook(hook string, config *c.Config, rotated []store.ConfigInput) error {
+	var keys []string
+	for _, input := range rotated {
+		keys = append(keys, input.Key())
+	}
+
+	cmd := exec.Command("sh", "-c", hook)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("SAFEBOX_STAGE=%s", config.Stage),
+		fmt.Sprintf("SAFEBOX_SERVICE=%s", config.Service),
+		fmt.Sprintf("SAFEBOX_ROTATED=%s", strings.Join(keys, ",")),
+	)
+
+	return cmd.Run()
+}
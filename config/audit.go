@@ -0,0 +1,41 @@
+package config
+
+import (
+	"time"
+
+	"github.com/adikari/safebox/v2/util"
+)
+
+type Audit struct {
+	MaxAge string `yaml:"max-age"` // default max age of secrets. eg. 90d
+}
+
+// addMaxAge records the max age of the secret from the entry or the audit
+// default
+func (c *Config) addMaxAge(name string, entry rawEntry, defaultMaxAge string) error {
+	age := entry.MaxAge
+
+	if age == "" {
+		age = defaultMaxAge
+	}
+
+	if age == "" {
+		return nil
+	}
+
+	d, err := util.ParseAge(age)
+
+	if err != nil {
+		return err
+	}
+
+	c.MaxAges[name] = d
+
+	return nil
+}
+
+// MaxAge returns the max age of the secret, if it has one
+func (c *Config) MaxAge(name string) (time.Duration, bool) {
+	d, ok := c.MaxAges[name]
+	return d, ok
+}
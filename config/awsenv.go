@@ -0,0 +1,45 @@
+package config
+
+import (
+	"github.com/adikari/safebox/v2/aws"
+	a "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// AwsEnv looks up the region, account and cloudformation outputs that are
+// available for interpolation with the aws providers
+type AwsEnv interface {
+	Region() string
+	Account() (string, error)
+	StackOutputs(stacks []string) (map[string]string, error)
+}
+
+type sessionEnv struct {
+	session *session.Session
+}
+
+// NewAwsEnv returns the env of the aws session in the region. The region of
+// the aws profile is used when it is empty
+func NewAwsEnv(region string) AwsEnv {
+	return &sessionEnv{session: aws.NewSession(a.Config{Region: &region})}
+}
+
+func (e *sessionEnv) Region() string {
+	return *e.session.Config.Region
+}
+
+func (e *sessionEnv) Account() (string, error) {
+	st := aws.NewSts(e.session)
+	id, err := st.GetCallerIdentity()
+
+	if err != nil {
+		return "", err
+	}
+
+	return *id.Account, nil
+}
+
+func (e *sessionEnv) StackOutputs(stacks []string) (map[string]string, error) {
+	cf := aws.NewCloudformation(e.session)
+	return cf.GetOutputs(stacks)
+}
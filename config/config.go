@@ -1,19 +1,16 @@
 package config
 
 import (
-	"bytes"
 	"fmt"
-	"html/template"
 	"io/ioutil"
 	"os"
 	"os/user"
 	"path/filepath"
 	"strings"
+	"time"
 
-	"github.com/adikari/safebox/v2/aws"
 	"github.com/adikari/safebox/v2/store"
 	"github.com/adikari/safebox/v2/util"
-	a "github.com/aws/aws-sdk-go/aws"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
 )
@@ -23,41 +20,158 @@ type rawConfig struct {
 	Service              string
 	Prefix               string
 	Generate             []Generate `yaml:"generate"`
-	Config               map[string]map[string]string
-	Secret               map[string]map[string]string
-	CloudformationStacks []string `yaml:"cloudformation-stacks"`
-	Region               string   `yaml:"region"`
-	DBDir                string   `yaml:"db_dir"`
+	Config               map[string]map[string]rawEntry
+	Secret               map[string]map[string]rawEntry
+	CloudformationStacks []string              `yaml:"cloudformation-stacks"`
+	Region               string                `yaml:"region"`
+	Regions              []string              `yaml:"regions"`
+	DBDir                string                `yaml:"db_dir"`
+	Gpg                  Gpg                   `yaml:"gpg"`
+	Vault                Vault                 `yaml:"vault"`
+	Tags                 map[string]string     `yaml:"tags"`
+	KmsKeyId             stageValue            `yaml:"kms-key-id"`
+	Rotation             Rotation              `yaml:"rotation"`
+	Audit                Audit                 `yaml:"audit"`
+	Vars                 map[string]stageValue `yaml:"vars"`
+	Include              []string              `yaml:"include"`
+	Extends              string                `yaml:"extends"`
+}
+
+// stageValue is a setting that is either a single value or a map of values
+// per stage that falls back to defaults
+type stageValue map[string]string
+
+func (v *stageValue) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var single string
+
+	if err := unmarshal(&single); err == nil {
+		*v = stageValue{"defaults": single}
+		return nil
+	}
+
+	values := map[string]string{}
+
+	if err := unmarshal(&values); err != nil {
+		return err
+	}
+
+	*v = values
+	return nil
+}
+
+func (v stageValue) forStage(stage string) string {
+	value, _ := v.lookup(stage)
+	return value
+}
+
+// lookup returns the value for the stage and whether the stage or defaults
+// has a value
+func (v stageValue) lookup(stage string) (string, bool) {
+	if value, ok := v[stage]; ok {
+		return value, true
+	}
+
+	value, ok := v["defaults"]
+	return value, ok
+}
+
+// rawEntry is a key under config or secret. It is either a plain string, which
+// is the value of a config or the description of a secret, or a map with the
+// value and settings of the key
+type rawEntry struct {
+	Value       string            `yaml:"value"`
+	Description string            `yaml:"description"`
+	Tags        map[string]string `yaml:"tags"`
+	Type        string            `yaml:"type"`
+	Enum        []string          `yaml:"enum"`
+	Pattern     string            `yaml:"pattern"`
+	Required    required          `yaml:"required"`
+	Generator   *Generator        `yaml:"generator"`
+	Ref         string            `yaml:"ref"`
+	MaxAge      string            `yaml:"max-age"`
+}
+
+func (e *rawEntry) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&e.Value); err == nil {
+		return nil
+	}
+
+	type plain rawEntry
+	return unmarshal((*plain)(e))
+}
+
+// description of a secret entry
+func (e rawEntry) description() string {
+	if e.Description != "" {
+		return e.Description
+	}
+
+	return e.Value
 }
 
 type Config struct {
-	Provider string
-	Service  string
-	Stage    string
-	Prefix   string
-	Generate []Generate
-	Region   string
-	All      []store.ConfigInput
-	Configs  []store.ConfigInput
-	Secrets  []store.ConfigInput
-	Stacks   []string
-	Filepath string
+	Provider   string
+	Service    string
+	Stage      string
+	Prefix     string
+	Generate   []Generate
+	Region     string
+	Regions    []string // regions to replicate to, the primary region first
+	All        []store.ConfigInput
+	Configs    []store.ConfigInput
+	Secrets    []store.ConfigInput
+	Stacks     []string
+	Filepath   string
+	Gpg        Gpg
+	Vault      Vault
+	KmsKeyId   string
+	Tags       map[string]string        // top level tags. keys not in the config file get only these
+	Schemas    map[string]Schema        // schema of the keys that declare one, by name
+	Generators map[string]Generator     // generator of the secrets that declare one, by name
+	MaxAges    map[string]time.Duration // max age of the secrets, by name
+	Refs       []Ref                    // keys read from params outside of the service
+	Rotation   Rotation
+}
+
+type Rotation struct {
+	PostHook string `yaml:"post-hook"` // command to run after secrets are rotated
+}
+
+type Vault struct {
+	Address   string `yaml:"address"`
+	Mount     string `yaml:"mount"`
+	Namespace string `yaml:"namespace"`
+}
+
+type Gpg struct {
+	Recipients []string `yaml:"recipients"`
+	Keyring    string   `yaml:"keyring"`
+	KeyFiles   []string `yaml:"key-files"`
 }
 
 type Generate struct {
-	Type string
-	Path string
+	Type      string
+	Path      string
+	Name      string // name of the k8s-secret and k8s-configmap manifest
+	Namespace string // namespace of the k8s-secret and k8s-configmap manifest
 }
 
 type LoadConfigInput struct {
-	Path  string
-	Stage string
+	Path      string
+	Stage     string
+	Variables map[string]string // overrides of the variables for interpolation
+	// GetStore instantiates the store read by ssm in interpolation. Defaults
+	// to store.GetStore
+	GetStore func(store.StoreConfig) (store.Store, error)
+	// GetAwsEnv returns the aws env of the region for interpolation. Defaults
+	// to NewAwsEnv
+	GetAwsEnv func(region string) AwsEnv
 }
 
 var defaultConfigPaths = []string{"safebox.yml", "safebox.yaml"}
 
 func Load(param LoadConfigInput) (*Config, error) {
-	yamlFile, err := readConfigFile(param.Path)
+	yamlFile, path, err := readMergedConfig(param.Path)
 
 	if err != nil {
 		return nil, fmt.Errorf(err.Error())
@@ -79,20 +193,47 @@ func Load(param LoadConfigInput) (*Config, error) {
 	}
 
 	c := Config{
-		Service:  rc.Service,
-		Stage:    param.Stage,
-		Provider: rc.Provider,
+		Service:    rc.Service,
+		Stage:      param.Stage,
+		Provider:   rc.Provider,
+		Schemas:    map[string]Schema{},
+		Generators: map[string]Generator{},
+		MaxAges:    map[string]time.Duration{},
+		Rotation:   rc.Rotation,
 	}
 
 	if c.Provider == "" {
 		c.Provider = util.SsmProvider
 	}
 
-	if c.Provider == util.GpgProvider {
-		c.Filepath = getFilePath(c, rc)
+	c.Regions = getRegions(rc)
+
+	if len(c.Regions) > 0 {
+		rc.Region = c.Regions[0]
+	}
+
+	// settings of all providers are loaded so that configs can be migrated to
+	// another provider
+	c.Filepath = getFilePath(c, rc)
+	c.Gpg = getGpg(rc.Gpg)
+	c.Vault = rc.Vault
+
+	vars, err := loadVars(rc, filepath.Dir(path), param.Stage)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load vars")
+	}
+
+	getAwsEnv := param.GetAwsEnv
+	if getAwsEnv == nil {
+		getAwsEnv = NewAwsEnv
 	}
 
-	variables, err := loadVariables(&c, rc)
+	variables, err := loadVariables(&c, rc, vars, param.Variables, getAwsEnv)
+
+	if c.Region == "" {
+		c.Region = rc.Region
+	}
 
 	if c.Region == "" {
 		c.Region = "local"
@@ -102,83 +243,258 @@ func Load(param LoadConfigInput) (*Config, error) {
 		return nil, errors.Wrap(err, "failed to load variables for interpolation")
 	}
 
-	c.Prefix, err = Interpolate(getPrefix(param.Stage, c.Service, rc.Prefix), variables)
+	in := newInterpolator(variables, &c, rc)
+
+	if param.GetStore != nil {
+		in.getStore = param.GetStore
+	}
+
+	c.Prefix, err = in.interpolate(getPrefix(param.Stage, c.Service, rc.Prefix))
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to interpolate prefix")
 	}
 
+	c.KmsKeyId, err = in.interpolate(rc.KmsKeyId.forStage(param.Stage))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to interpolate kms-key-id")
+	}
+
+	// a key arn belongs to one region and every region store uses the same key
+	if len(c.Regions) > 1 && strings.HasPrefix(c.KmsKeyId, "arn:") {
+		return nil, errors.Errorf("kms-key-id %s is in a single region and can not be used with regions. use an alias that exists in every region, eg. alias/%s", c.KmsKeyId, c.Service)
+	}
+
+	tags, err := interpolateTags(rc.Tags, in)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to interpolate tags")
+	}
+
+	c.Tags = tags
+
 	for key, value := range rc.Config["defaults"] {
-		val, err := Interpolate(value, variables)
+		if value.Ref != "" {
+			if err := c.addRef(formatPath(c.Prefix, key), value.Ref, false, in); err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("failed to interpolate config.defaults.%s.ref", key))
+			}
+			continue
+		}
+
+		val, err := in.interpolate(value.Value)
 
 		if err != nil {
 			return nil, errors.Wrap(err, fmt.Sprintf("failed to interpolate config.defaults.%s", key))
 		}
 
+		t, err := entryTags(tags, value, in)
+
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("failed to interpolate config.defaults.%s.tags", key))
+		}
+
+		if err := c.addSchema(formatPath(c.Prefix, key), value, false); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("invalid schema for config.defaults.%s", key))
+		}
+
 		c.Configs = append(c.Configs, store.ConfigInput{
-			Name:   formatPath(c.Prefix, key),
-			Value:  val,
-			Secret: false,
+			Name:        formatPath(c.Prefix, key),
+			Value:       val,
+			Description: value.Description,
+			Secret:      false,
+			Tags:        t,
 		})
 	}
 
 	for _, value := range rc.Generate {
-		path, err := Interpolate(value.Path, variables)
+		path, err := in.interpolate(value.Path)
 
 		if err != nil {
 			return nil, errors.Wrap(err, fmt.Sprintf("failed to interpolate generate: type: %s, path: %s", value.Type, value.Path))
 		}
 
+		name, err := in.interpolate(value.Name)
+
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("failed to interpolate generate: type: %s, name: %s", value.Type, value.Name))
+		}
+
+		namespace, err := in.interpolate(value.Namespace)
+
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("failed to interpolate generate: type: %s, namespace: %s", value.Type, value.Namespace))
+		}
+
 		c.Generate = append(c.Generate, Generate{
-			Type: value.Type,
-			Path: path,
+			Type:      value.Type,
+			Path:      path,
+			Name:      name,
+			Namespace: namespace,
 		})
 	}
 
 	for key, value := range rc.Config["shared"] {
-		val, err := Interpolate(value, variables)
+		if value.Ref != "" {
+			if err := c.addRef(formatSharedPath(param.Stage, key), value.Ref, false, in); err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("failed to interpolate config.shared.%s.ref", key))
+			}
+			continue
+		}
+
+		val, err := in.interpolate(value.Value)
 
 		if err != nil {
 			return nil, errors.Wrap(err, fmt.Sprintf("failed to interpolate config.shared.%s", key))
 		}
 
+		t, err := entryTags(tags, value, in)
+
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("failed to interpolate config.shared.%s.tags", key))
+		}
+
+		if err := c.addSchema(formatSharedPath(param.Stage, key), value, false); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("invalid schema for config.shared.%s", key))
+		}
+
 		c.Configs = append(c.Configs, store.ConfigInput{
-			Name:   formatSharedPath(param.Stage, key),
-			Value:  val,
-			Secret: false,
+			Name:        formatSharedPath(param.Stage, key),
+			Value:       val,
+			Description: value.Description,
+			Secret:      false,
+			Tags:        t,
 		})
 	}
 
 	for key, value := range rc.Config[param.Stage] {
+		if value.Ref != "" {
+			if err := c.addRef(formatPath(c.Prefix, key), value.Ref, false, in); err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("failed to interpolate config.%s.%s.ref", param.Stage, key))
+			}
+			continue
+		}
+
+		t, err := entryTags(tags, value, in)
+
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("failed to interpolate config.%s.%s.tags", param.Stage, key))
+		}
+
+		if err := c.addSchema(formatPath(c.Prefix, key), value, false); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("invalid schema for config.%s.%s", param.Stage, key))
+		}
+
+		c.removeRef(formatPath(c.Prefix, key))
+
 		c.Configs = append(c.Configs, store.ConfigInput{
-			Name:   formatPath(c.Prefix, key),
-			Value:  value,
-			Secret: false,
+			Name:        formatPath(c.Prefix, key),
+			Value:       value.Value,
+			Description: value.Description,
+			Secret:      false,
+			Tags:        t,
 		})
 	}
 
 	c.Configs = removeDuplicate(c.Configs)
 
 	for key, value := range rc.Secret["defaults"] {
+		if value.Ref != "" {
+			if err := c.addRef(formatPath(c.Prefix, key), value.Ref, true, in); err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("failed to interpolate secret.defaults.%s.ref", key))
+			}
+			continue
+		}
+
+		t, err := entryTags(tags, value, in)
+
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("failed to interpolate secret.defaults.%s.tags", key))
+		}
+
+		if err := c.addSchema(formatPath(c.Prefix, key), value, true); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("invalid schema for secret.defaults.%s", key))
+		}
+
+		if err := c.addGenerator(formatPath(c.Prefix, key), value); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("invalid generator for secret.defaults.%s", key))
+		}
+
+		if err := c.addMaxAge(formatPath(c.Prefix, key), value, rc.Audit.MaxAge); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("invalid max-age for secret.defaults.%s", key))
+		}
+
 		c.Secrets = append(c.Secrets, store.ConfigInput{
 			Name:        formatPath(c.Prefix, key),
-			Description: value,
+			Description: value.description(),
 			Secret:      true,
+			Tags:        t,
 		})
 	}
 
 	for key, value := range rc.Secret["shared"] {
+		if value.Ref != "" {
+			if err := c.addRef(formatSharedPath(param.Stage, key), value.Ref, true, in); err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("failed to interpolate secret.shared.%s.ref", key))
+			}
+			continue
+		}
+
+		t, err := entryTags(tags, value, in)
+
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("failed to interpolate secret.shared.%s.tags", key))
+		}
+
+		if err := c.addSchema(formatSharedPath(param.Stage, key), value, true); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("invalid schema for secret.shared.%s", key))
+		}
+
+		if err := c.addGenerator(formatSharedPath(param.Stage, key), value); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("invalid generator for secret.shared.%s", key))
+		}
+
+		if err := c.addMaxAge(formatSharedPath(param.Stage, key), value, rc.Audit.MaxAge); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("invalid max-age for secret.shared.%s", key))
+		}
+
 		c.Secrets = append(c.Secrets, store.ConfigInput{
 			Name:        formatSharedPath(param.Stage, key),
-			Description: value,
+			Description: value.description(),
 			Secret:      true,
+			Tags:        t,
 		})
 	}
 
+	if err := c.validatePasswords(); err != nil {
+		return nil, err
+	}
+
 	c.All = append(c.Secrets, c.Configs...)
 
 	return &c, nil
 }
 
+// StoreConfig returns the configuration to instantiate the store with
+func (c *Config) StoreConfig() store.StoreConfig {
+	keyFiles := c.Gpg.KeyFiles
+
+	if c.Gpg.Keyring != "" {
+		keyFiles = append([]string{c.Gpg.Keyring}, keyFiles...)
+	}
+
+	return store.StoreConfig{
+		Provider:   c.Provider,
+		Region:     c.Region,
+		FilePath:   c.Filepath,
+		Recipients: c.Gpg.Recipients,
+		KeyFiles:   keyFiles,
+		KmsKeyId:   c.KmsKeyId,
+		Regions:    c.Regions,
+		Address:    c.Vault.Address,
+		Mount:      c.Vault.Mount,
+		Namespace:  c.Vault.Namespace,
+	}
+}
+
 func formatSharedPath(stage string, key string) string {
 	if stage != "" {
 		return fmt.Sprintf("/%s/shared/%s", stage, key)
@@ -212,48 +528,121 @@ func validateConfig(rc rawConfig) error {
 		return fmt.Errorf("'provider' is missing")
 	}
 
+	if len(rc.Regions) > 0 && !util.IsAwsProvider(rc.Provider) {
+		return fmt.Errorf("'regions' is only supported by aws providers")
+	}
+
+	for stage, entries := range rc.Config {
+		for key, entry := range entries {
+			if entry.Generator != nil {
+				return fmt.Errorf("config.%s.%s: generator is only supported for secrets", stage, key)
+			}
+
+			if entry.Ref != "" && entry.Value != "" {
+				return fmt.Errorf("config.%s.%s: ref can not have a value", stage, key)
+			}
+		}
+	}
+
+	for stage, entries := range rc.Secret {
+		for key, entry := range entries {
+			if entry.Ref != "" && entry.Generator != nil {
+				return fmt.Errorf("secret.%s.%s: ref can not have a generator", stage, key)
+			}
+		}
+	}
+
 	return nil
 }
 
-// loadVariables for interpolation
-// TODO: in future as we support more stores, this many need to be refactored to handled each
-func loadVariables(c *Config, rc rawConfig) (map[string]string, error) {
-	if !util.IsAwsProvider(c.Provider) {
-		return map[string]string{}, nil
+// getRegions returns the region followed by the other regions to replicate
+// to. The first of regions is the primary region when region is not set
+func getRegions(rc rawConfig) []string {
+	if len(rc.Regions) <= 0 {
+		return nil
 	}
 
-	session := aws.NewSession(a.Config{Region: &rc.Region})
-	st := aws.NewSts(session)
-	c.Region = *session.Config.Region
-
-	id, err := st.GetCallerIdentity()
+	var regions []string
+	seen := map[string]bool{}
 
-	if err != nil {
-		return nil, errors.New("Failed to login to AWS")
+	for _, r := range append([]string{rc.Region}, rc.Regions...) {
+		if r != "" && !seen[r] {
+			seen[r] = true
+			regions = append(regions, r)
+		}
 	}
 
+	return regions
+}
+
+// loadVariables for interpolation. stage, service and vars are available with
+// every provider. aws providers add the region, account and stack outputs.
+// Overrides take precedence over everything else
+func loadVariables(c *Config, rc rawConfig, vars map[string]string, overrides map[string]string, getAwsEnv func(string) AwsEnv) (map[string]string, error) {
 	variables := map[string]string{
 		"stage":   c.Stage,
 		"service": c.Service,
-		"region":  c.Region,
-		"account": *id.Account,
 	}
 
+	if util.IsAwsProvider(c.Provider) {
+		if err := loadAwsVariables(c, rc, variables, getAwsEnv(rc.Region)); err != nil {
+			return nil, err
+		}
+	}
+
+	// vars are interpolated with the variables above only so that the result
+	// does not depend on the order of the vars
+	interpolated := map[string]string{}
+
+	for key, value := range vars {
+		v, err := Interpolate(value, variables)
+
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("failed to interpolate vars.%s", key))
+		}
+
+		interpolated[key] = v
+	}
+
+	for key, value := range interpolated {
+		variables[key] = value
+	}
+
+	for key, value := range overrides {
+		variables[key] = value
+	}
+
+	return variables, nil
+}
+
+// loadAwsVariables adds the region, account and outputs of the cloudformation
+// stacks to the variables
+func loadAwsVariables(c *Config, rc rawConfig, variables map[string]string, env AwsEnv) error {
+	c.Region = env.Region()
+
+	account, err := env.Account()
+
+	if err != nil {
+		return errors.New("Failed to login to AWS")
+	}
+
+	variables["region"] = c.Region
+	variables["account"] = account
+
 	for _, name := range rc.CloudformationStacks {
 		value, err := Interpolate(name, variables)
 		if err != nil {
-			return nil, errors.Wrap(err, fmt.Sprintf("failed to interpolate cloudformation-stacks[%s]", name))
+			return errors.Wrap(err, fmt.Sprintf("failed to interpolate cloudformation-stacks[%s]", name))
 		}
 		c.Stacks = append(c.Stacks, value)
 	}
 
 	// add cloudformation outputs to variables available for interpolation
 	if len(c.Stacks) > 0 {
-		cf := aws.NewCloudformation(session)
-		outputs, err := cf.GetOutputs(c.Stacks)
+		outputs, err := env.StackOutputs(c.Stacks)
 
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		for key, value := range outputs {
@@ -261,20 +650,44 @@ func loadVariables(c *Config, rc rawConfig) (map[string]string, error) {
 		}
 	}
 
-	return variables, nil
+	return nil
 }
 
-func Interpolate(value string, variables map[string]string) (string, error) {
-	var result bytes.Buffer
-	tmpl, _ := template.New("interpolate").Option("missingkey=error").Parse(value)
+func interpolateTags(tags map[string]string, in *interpolator) (map[string]string, error) {
+	result := map[string]string{}
 
-	err := tmpl.Execute(&result, variables)
+	for key, value := range tags {
+		val, err := in.interpolate(value)
+
+		if err != nil {
+			return nil, errors.Wrap(err, key)
+		}
+
+		result[key] = val
+	}
+
+	return result, nil
+}
+
+// entryTags merges the tags of an entry with the top level tags
+func entryTags(tags map[string]string, entry rawEntry, in *interpolator) (map[string]string, error) {
+	own, err := interpolateTags(entry.Tags, in)
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return result.String(), nil
+	result := map[string]string{}
+
+	for key, value := range tags {
+		result[key] = value
+	}
+
+	for key, value := range own {
+		result[key] = value
+	}
+
+	return result, nil
 }
 
 func removeDuplicate(input []store.ConfigInput) []store.ConfigInput {
@@ -294,22 +707,23 @@ loop:
 	return unique
 }
 
-func readConfigFile(path string) ([]byte, error) {
+// readConfigFile returns the content and the path of the config file
+func readConfigFile(path string) ([]byte, string, error) {
 	if path != "" {
 		s, err := ioutil.ReadFile(path)
 		if err != nil {
-			return nil, fmt.Errorf("missing file %s", path)
+			return nil, "", fmt.Errorf("missing file %s", path)
 		}
-		return s, nil
+		return s, path, nil
 	}
 
 	for _, c := range defaultConfigPaths {
 		if s, err := ioutil.ReadFile(c); err == nil {
-			return s, nil
+			return s, c, nil
 		}
 	}
 
-	return nil, fmt.Errorf("missing file %s", strings.Join(defaultConfigPaths, " or "))
+	return nil, "", fmt.Errorf("missing file %s", strings.Join(defaultConfigPaths, " or "))
 }
 
 func getFilePath(config Config, rc rawConfig) string {
@@ -323,15 +737,7 @@ func getFilePath(config Config, rc rawConfig) string {
 		d = exPath
 	}
 
-	dir := filepath.Clean(d)
-
-	usr, _ := user.Current()
-	homedir := usr.HomeDir
-	if dir == "~" {
-		dir = homedir
-	} else if strings.HasPrefix(dir, "~/") {
-		dir = filepath.Join(homedir, dir[2:])
-	}
+	dir := expandHome(filepath.Clean(d))
 
 	filename := fmt.Sprintf("%s-%s", config.Stage, config.Service)
 	if config.Stage == "" {
@@ -340,3 +746,34 @@ func getFilePath(config Config, rc rawConfig) string {
 
 	return filepath.Join(dir, filename)
 }
+
+func getGpg(g Gpg) Gpg {
+	result := Gpg{
+		Recipients: g.Recipients,
+	}
+
+	if g.Keyring != "" {
+		result.Keyring = expandHome(g.Keyring)
+	}
+
+	for _, f := range g.KeyFiles {
+		result.KeyFiles = append(result.KeyFiles, expandHome(f))
+	}
+
+	return result
+}
+
+func expandHome(path string) string {
+	usr, _ := user.Current()
+	homedir := usr.HomeDir
+
+	if path == "~" {
+		return homedir
+	}
+
+	if strings.HasPrefix(path, "~/") {
+		return filepath.Join(homedir, path[2:])
+	}
+
+	return path
+}
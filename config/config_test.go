@@ -0,0 +1,61 @@
+package config_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adikari/safebox/v2/config"
+)
+
+type fakeAwsEnv struct{}
+
+func (fakeAwsEnv) Region() string { return "us-east-1" }
+
+func (fakeAwsEnv) Account() (string, error) { return "111111111111", nil }
+
+func (fakeAwsEnv) StackOutputs([]string) (map[string]string, error) { return nil, nil }
+
+func load(t *testing.T, content string) (*config.Config, error) {
+	t.Helper()
+
+	dir := writeFiles(t, map[string]string{"safebox.yml": content})
+
+	return config.Load(config.LoadConfigInput{
+		Path:      filepath.Join(dir, "safebox.yml"),
+		Stage:     "dev",
+		GetAwsEnv: func(string) config.AwsEnv { return fakeAwsEnv{} },
+	})
+}
+
+func TestLoadKmsKeyWithRegions(t *testing.T) {
+	_, err := load(t, `
+service: svc
+provider: ssm
+regions: [us-east-1, us-west-2]
+kms-key-id: arn:aws:kms:us-east-1:111111111111:key/some-key-id
+`)
+
+	if err == nil || !strings.Contains(err.Error(), "can not be used with regions") {
+		t.Fatalf("expected key arn to be rejected with regions, got %v", err)
+	}
+
+	c, err := load(t, `
+service: svc
+provider: ssm
+regions: [us-east-1, us-west-2]
+kms-key-id: alias/svc
+`)
+
+	if err != nil || c.KmsKeyId != "alias/svc" {
+		t.Fatalf("expected alias to be accepted, got %v, %v", c, err)
+	}
+
+	if _, err := load(t, `
+service: svc
+provider: ssm
+kms-key-id: arn:aws:kms:us-east-1:111111111111:key/some-key-id
+`); err != nil {
+		t.Fatalf("expected key arn to be accepted with a single region, got %v", err)
+	}
+}
@@ -0,0 +1,252 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// appendedKeys are the lists of the base file that the lists of the child are
+// appended to. Other lists are replaced by the child
+var appendedKeys = []string{"generate", "cloudformation-stacks", "include"}
+
+// itemKeys identify the items of appended lists. An item of the child replaces
+// the item of the base with the same key instead of being appended
+var itemKeys = map[string]string{"generate": "path"}
+
+// Render returns the config file merged with the files it extends
+func Render(path string) ([]byte, error) {
+	data, _, err := readMergedConfig(path)
+	return data, err
+}
+
+// readMergedConfig returns the config file merged with the files it extends
+// and the path of the config file
+func readMergedConfig(path string) ([]byte, string, error) {
+	data, path, err := readConfigFile(path)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	merged, err := extend(data, path, nil)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	out, err := yaml.Marshal(merged)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	return out, path, nil
+}
+
+// extend merges the config file into the file it extends. Seen are the files
+// extended so far
+func extend(data []byte, path string, seen []string) (yaml.MapSlice, error) {
+	abs, err := filepath.Abs(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range seen {
+		if s == abs {
+			return nil, errors.Errorf("cycle in extends: %s", strings.Join(append(seen, abs), " -> "))
+		}
+	}
+
+	seen = append(seen, abs)
+
+	var child yaml.MapSlice
+
+	if err := yaml.Unmarshal(data, &child); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("could not parse safebox config file %s", path))
+	}
+
+	i := indexOf(child, "extends")
+
+	if i < 0 {
+		return child, nil
+	}
+
+	base, ok := child[i].Value.(string)
+
+	if !ok || base == "" {
+		return nil, errors.Errorf("extends in %s must be the path of a config file", path)
+	}
+
+	child = append(child[:i:i], child[i+1:]...)
+
+	basePath := relativeTo(filepath.Dir(abs), base)
+	baseData, err := ioutil.ReadFile(basePath)
+
+	if err != nil {
+		return nil, fmt.Errorf("missing file %s extended by %s", base, path)
+	}
+
+	parent, err := extend(baseData, basePath, seen)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rebaseIncludes(parent, filepath.Dir(basePath))
+
+	return mergeConfigs(parent, child), nil
+}
+
+// mergeConfigs deep merges the child into the base. The child takes precedence
+func mergeConfigs(base yaml.MapSlice, child yaml.MapSlice) yaml.MapSlice {
+	result := append(yaml.MapSlice{}, base...)
+
+	for _, item := range child {
+		i := indexOf(result, item.Key)
+
+		switch {
+		case i < 0:
+			result = append(result, item)
+		case isAppended(item.Key):
+			result[i].Value = appendList(result[i].Value, item.Value, itemKeys[item.Key.(string)])
+		default:
+			result[i].Value = mergeValues(result[i].Value, item.Value)
+		}
+	}
+
+	return result
+}
+
+// mergeValues merges maps recursively. Other values of the child replace the
+// base unless they are empty
+func mergeValues(base interface{}, child interface{}) interface{} {
+	if child == nil {
+		return base
+	}
+
+	b, bok := base.(yaml.MapSlice)
+	c, cok := child.(yaml.MapSlice)
+
+	if !bok || !cok {
+		return child
+	}
+
+	result := append(yaml.MapSlice{}, b...)
+
+	for _, item := range c {
+		if i := indexOf(result, item.Key); i >= 0 {
+			result[i].Value = mergeValues(result[i].Value, item.Value)
+		} else {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
+// appendList appends the items of the child that are not in the base. When
+// the items are identified by a key, the item of the child replaces the item
+// of the base with the same key
+func appendList(base interface{}, child interface{}, key string) interface{} {
+	b, bok := base.([]interface{})
+	c, cok := child.([]interface{})
+
+	if !bok || !cok {
+		return mergeValues(base, child)
+	}
+
+	result := append([]interface{}{}, b...)
+
+loop:
+	for _, item := range c {
+		for n, existing := range result {
+			if reflect.DeepEqual(existing, item) {
+				continue loop
+			}
+
+			if key != "" && sameItem(existing, item, key) {
+				result[n] = item
+				continue loop
+			}
+		}
+
+		result = append(result, item)
+	}
+
+	return result
+}
+
+// sameItem reports whether both items are maps with the same value of the key
+func sameItem(a interface{}, b interface{}, key string) bool {
+	am, aok := a.(yaml.MapSlice)
+	bm, bok := b.(yaml.MapSlice)
+
+	if !aok || !bok {
+		return false
+	}
+
+	i, j := indexOf(am, key), indexOf(bm, key)
+
+	return i >= 0 && j >= 0 && reflect.DeepEqual(am[i].Value, bm[j].Value)
+}
+
+// rebaseIncludes makes the include paths of a base file relative to its own
+// directory instead of the directory of the child
+func rebaseIncludes(config yaml.MapSlice, dir string) {
+	i := indexOf(config, "include")
+
+	if i < 0 {
+		return
+	}
+
+	includes, ok := config[i].Value.([]interface{})
+
+	if !ok {
+		return
+	}
+
+	for n, include := range includes {
+		if path, ok := include.(string); ok {
+			includes[n] = relativeTo(dir, path)
+		}
+	}
+}
+
+// relativeTo returns the path relative to the directory unless it is absolute
+// or in the home directory
+func relativeTo(dir string, path string) string {
+	path = expandHome(path)
+
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(dir, path)
+}
+
+func indexOf(config yaml.MapSlice, key interface{}) int {
+	for i, item := range config {
+		if item.Key == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func isAppended(key interface{}) bool {
+	for _, k := range appendedKeys {
+		if k == key {
+			return true
+		}
+	}
+
+	return false
+}
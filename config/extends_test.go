@@ -0,0 +1,185 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/adikari/safebox/v2/config"
+	"gopkg.in/yaml.v2"
+)
+
+// writeFiles writes the files relative to a temporary directory and returns
+// the directory
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	return dir
+}
+
+func render(t *testing.T, path string) map[string]interface{} {
+	t.Helper()
+
+	data, err := config.Render(path)
+
+	if err != nil {
+		t.Fatalf("failed to render %s: %v", path, err)
+	}
+
+	var result map[string]interface{}
+
+	if err := yaml.Unmarshal(data, &result); err != nil {
+		t.Fatalf("failed to parse rendered config %s: %v", data, err)
+	}
+
+	return result
+}
+
+func TestExtendsChildTakesPrecedence(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"base.yml": `
+service: base
+provider: ssm
+config:
+  defaults:
+    LOG_LEVEL: info
+    DB_HOST: db
+`,
+		"safebox.yml": `
+extends: base.yml
+service: orders
+config:
+  defaults:
+    LOG_LEVEL: debug
+`,
+	})
+
+	result := render(t, filepath.Join(dir, "safebox.yml"))
+
+	if result["service"] != "orders" || result["provider"] != "ssm" {
+		t.Errorf("expected service of the child and provider of the base, got %v", result)
+	}
+
+	defaults := result["config"].(map[interface{}]interface{})["defaults"]
+	expected := map[interface{}]interface{}{"LOG_LEVEL": "debug", "DB_HOST": "db"}
+
+	if !reflect.DeepEqual(defaults, expected) {
+		t.Errorf("expected defaults %v, got %v", expected, defaults)
+	}
+
+	if _, ok := result["extends"]; ok {
+		t.Errorf("expected extends to be removed, got %v", result["extends"])
+	}
+}
+
+func TestExtendsAppendsLists(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"base.yml": `
+service: base
+cloudformation-stacks:
+  - shared
+  - network
+generate:
+  - type: dotenv
+    path: .env
+  - type: json
+    path: config.json
+`,
+		"safebox.yml": `
+extends: base.yml
+service: orders
+cloudformation-stacks:
+  - network
+  - orders
+generate:
+  - type: yaml
+    path: config.json
+  - type: types-node
+    path: env.d.ts
+`,
+	})
+
+	result := render(t, filepath.Join(dir, "safebox.yml"))
+
+	stacks := result["cloudformation-stacks"]
+	expected := []interface{}{"shared", "network", "orders"}
+
+	if !reflect.DeepEqual(stacks, expected) {
+		t.Errorf("expected stacks %v, got %v", expected, stacks)
+	}
+
+	var generate []string
+	for _, g := range result["generate"].([]interface{}) {
+		m := g.(map[interface{}]interface{})
+		generate = append(generate, m["type"].(string)+":"+m["path"].(string))
+	}
+
+	if strings.Join(generate, ",") != "dotenv:.env,yaml:config.json,types-node:env.d.ts" {
+		t.Errorf("expected generate of the child to replace the same path, got %v", generate)
+	}
+}
+
+func TestExtendsRebasesIncludes(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"shared/base.yml": `
+service: base
+include:
+  - vars.yml
+`,
+		"services/orders/safebox.yml": `
+extends: ../../shared/base.yml
+service: orders
+include:
+  - local.yml
+`,
+	})
+
+	result := render(t, filepath.Join(dir, "services", "orders", "safebox.yml"))
+
+	expected := []interface{}{filepath.Join(dir, "shared", "vars.yml"), "local.yml"}
+
+	if !reflect.DeepEqual(result["include"], expected) {
+		t.Errorf("expected includes %v, got %v", expected, result["include"])
+	}
+}
+
+func TestExtendsCycle(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.yml": "extends: b.yml\nservice: a\n",
+		"b.yml": "extends: a.yml\nservice: b\n",
+	})
+
+	_, err := config.Render(filepath.Join(dir, "a.yml"))
+
+	if err == nil || !strings.Contains(err.Error(), "cycle in extends") {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+}
+
+func TestExtendsMissingBase(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"safebox.yml": "extends: missing.yml\nservice: orders\n",
+	})
+
+	_, err := config.Render(filepath.Join(dir, "safebox.yml"))
+
+	if err == nil || !strings.Contains(err.Error(), "missing file missing.yml") {
+		t.Fatalf("expected missing file error, got %v", err)
+	}
+}
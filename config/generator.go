@@ -0,0 +1,298 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"path"
+	"strings"
+
+	"github.com/adikari/safebox/v2/store"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	GeneratorAlphanumeric = "alphanumeric"
+	GeneratorUuid         = "uuid"
+	GeneratorHex          = "hex"
+	GeneratorBase64       = "base64"
+	GeneratorRsa          = "rsa"
+	GeneratorEd25519      = "ed25519"
+	GeneratorBcrypt       = "bcrypt"
+
+	defaultGeneratorLength = 32
+	defaultRsaBits         = 2048
+)
+
+const alphanumeric = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// Generator generates the value of a secret. It is either the name of the
+// generator or a map with the type and its settings
+type Generator struct {
+	Type string `yaml:"type"`
+	// Length of alphanumeric values and generated bcrypt passwords, or the
+	// number of random bytes of hex and base64 values
+	Length int `yaml:"length"`
+	// Bits of rsa keys
+	Bits int `yaml:"bits"`
+	// Cost of bcrypt hashes
+	Cost int `yaml:"cost"`
+	// Password is the key of the secret the password of a bcrypt hash is
+	// written to. Defaults to the key of the hash with a _PASSWORD suffix
+	Password string `yaml:"password"`
+}
+
+func (g *Generator) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&g.Type); err == nil {
+		return nil
+	}
+
+	type plain Generator
+	return unmarshal((*plain)(g))
+}
+
+func (g Generator) validate() error {
+	switch g.Type {
+	case GeneratorAlphanumeric, GeneratorUuid, GeneratorHex, GeneratorBase64, GeneratorEd25519:
+	case GeneratorRsa:
+		if g.Bits != 0 && g.Bits < 2048 {
+			return fmt.Errorf("rsa keys must be at least 2048 bits")
+		}
+	case GeneratorBcrypt:
+		if g.Cost != 0 && (g.Cost < bcrypt.MinCost || g.Cost > bcrypt.MaxCost) {
+			return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	default:
+		return fmt.Errorf("unsupported generator '%s'", g.Type)
+	}
+
+	if g.Length < 0 {
+		return fmt.Errorf("generator length must be positive")
+	}
+
+	return nil
+}
+
+// Generate a new random value. Bcrypt generators also return the generated
+// password, the other generators no password
+func (g Generator) Generate() (string, string, error) {
+	if g.Type == GeneratorBcrypt {
+		length := g.Length
+		if length == 0 {
+			length = defaultGeneratorLength
+		}
+
+		password, err := randomAlphanumeric(length)
+
+		if err != nil {
+			return "", "", err
+		}
+
+		hash, err := g.Hash(password)
+
+		return hash, password, err
+	}
+
+	value, err := g.generate()
+
+	return value, "", err
+}
+
+// Hash returns the bcrypt hash of the password
+func (g Generator) Hash(password string) (string, error) {
+	cost := g.Cost
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+
+	return string(hash), err
+}
+
+func (g Generator) generate() (string, error) {
+	length := g.Length
+	if length == 0 {
+		length = defaultGeneratorLength
+	}
+
+	switch g.Type {
+	case GeneratorAlphanumeric:
+		return randomAlphanumeric(length)
+	case GeneratorUuid:
+		return randomUuid()
+	case GeneratorHex:
+		b, err := randomBytes(length)
+		return hex.EncodeToString(b), err
+	case GeneratorBase64:
+		b, err := randomBytes(length)
+		return base64.StdEncoding.EncodeToString(b), err
+	case GeneratorRsa:
+		bits := g.Bits
+		if bits == 0 {
+			bits = defaultRsaBits
+		}
+
+		key, err := rsa.GenerateKey(rand.Reader, bits)
+
+		if err != nil {
+			return "", err
+		}
+
+		return keypairPem(key, &key.PublicKey)
+	case GeneratorEd25519:
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+
+		if err != nil {
+			return "", err
+		}
+
+		return keypairPem(private, public)
+	}
+
+	return "", fmt.Errorf("unsupported generator '%s'", g.Type)
+}
+
+// Generator returns the generator of the secret, if it declares one
+func (c *Config) Generator(name string) (Generator, bool) {
+	g, ok := c.Generators[name]
+	return g, ok
+}
+
+// PasswordSecret returns the secret the password of a bcrypt secret is written
+// to. It is declared next to the bcrypt secret
+func (c *Config) PasswordSecret(name string) (store.ConfigInput, bool) {
+	g, ok := c.Generators[name]
+
+	if !ok || g.Type != GeneratorBcrypt {
+		return store.ConfigInput{}, false
+	}
+
+	password := path.Join(path.Dir(name), g.Password)
+
+	for _, input := range c.Secrets {
+		if input.Name == password {
+			return input, true
+		}
+	}
+
+	return store.ConfigInput{}, false
+}
+
+// HashedBy returns the bcrypt secret whose password is written to the secret
+func (c *Config) HashedBy(name string) (string, bool) {
+	for hash := range c.Generators {
+		if password, ok := c.PasswordSecret(hash); ok && password.Name == name {
+			return hash, true
+		}
+	}
+
+	return "", false
+}
+
+// validatePasswords checks the password secret of every bcrypt secret is
+// declared and is not generated by itself
+func (c *Config) validatePasswords() error {
+	for name, g := range c.Generators {
+		if g.Type != GeneratorBcrypt {
+			continue
+		}
+
+		password, ok := c.PasswordSecret(name)
+
+		if !ok {
+			return fmt.Errorf("%s writes its password to %s which is not declared under secret", name, path.Join(path.Dir(name), g.Password))
+		}
+
+		if _, ok := c.Generators[password.Name]; ok {
+			return fmt.Errorf("%s is the password of %s and can not have a generator", password.Name, name)
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) addGenerator(name string, entry rawEntry) error {
+	if entry.Generator == nil {
+		return nil
+	}
+
+	g := *entry.Generator
+	g.Type = strings.ToLower(g.Type)
+
+	if g.Type == GeneratorBcrypt && g.Password == "" {
+		g.Password = path.Base(name) + "_PASSWORD"
+	}
+
+	if err := g.validate(); err != nil {
+		return err
+	}
+
+	c.Generators[name] = g
+
+	return nil
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	return b, err
+}
+
+func randomAlphanumeric(n int) (string, error) {
+	max := big.NewInt(int64(len(alphanumeric)))
+	b := make([]byte, n)
+
+	for i := range b {
+		j, err := rand.Int(rand.Reader, max)
+
+		if err != nil {
+			return "", err
+		}
+
+		b[i] = alphanumeric[j.Int64()]
+	}
+
+	return string(b), nil
+}
+
+// randomUuid generates a version 4 uuid
+func randomUuid() (string, error) {
+	b, err := randomBytes(16)
+
+	if err != nil {
+		return "", err
+	}
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// keypairPem encodes the private key as PKCS #8 followed by the public key
+func keypairPem(private interface{}, public interface{}) (string, error) {
+	privateDer, err := x509.MarshalPKCS8PrivateKey(private)
+
+	if err != nil {
+		return "", err
+	}
+
+	publicDer, err := x509.MarshalPKIXPublicKey(public)
+
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	pem.Encode(&b, &pem.Block{Type: "PRIVATE KEY", Bytes: privateDer})
+	pem.Encode(&b, &pem.Block{Type: "PUBLIC KEY", Bytes: publicDer})
+
+	return b.String(), nil
+}
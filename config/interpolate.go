@@ -0,0 +1,187 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/adikari/safebox/v2/store"
+	"github.com/pkg/errors"
+)
+
+// interpolator executes values as templates with the variables and the
+// template functions
+type interpolator struct {
+	variables map[string]string
+	config    *Config // config being loaded. ref and ssm need it
+	rc        rawConfig
+	resolving []string          // keys being resolved by ref
+	cycle     error             // cycle found by ref. returned as is instead of nested template errors
+	params    map[string]string // params read by ssm, by path
+	store     store.Store
+	getStore  func(store.StoreConfig) (store.Store, error)
+}
+
+func newInterpolator(variables map[string]string, c *Config, rc rawConfig) *interpolator {
+	return &interpolator{
+		variables: variables,
+		config:    c,
+		rc:        rc,
+		params:    map[string]string{},
+		getStore:  store.GetStore,
+	}
+}
+
+// Interpolate executes the value as a template with the variables. Functions
+// that read other keys or params are not available
+func Interpolate(value string, variables map[string]string) (string, error) {
+	return newInterpolator(variables, nil, rawConfig{}).interpolate(value)
+}
+
+func (i *interpolator) interpolate(value string) (string, error) {
+	// a cycle found while interpolating another value must not be returned
+	// for this one. refs interpolate nested values while resolving is set
+	if len(i.resolving) == 0 {
+		i.cycle = nil
+	}
+
+	var result bytes.Buffer
+
+	tmpl, err := template.New("interpolate").Option("missingkey=error").Funcs(i.funcs()).Parse(value)
+
+	if err != nil {
+		return "", err
+	}
+
+	if err := tmpl.Execute(&result, i.variables); err != nil {
+		if i.cycle != nil {
+			return "", i.cycle
+		}
+
+		return "", err
+	}
+
+	return result.String(), nil
+}
+
+func (i *interpolator) funcs() template.FuncMap {
+	return template.FuncMap{
+		"env":     os.Getenv,
+		"ref":     i.ref,
+		"ssm":     i.ssm,
+		"default": defaultValue,
+		"lower":   strings.ToLower,
+		"b64enc":  b64enc,
+		"join":    join,
+	}
+}
+
+// ref returns the value of another config key. Keys under config.shared can
+// be named shared/KEY
+func (i *interpolator) ref(key string) (string, error) {
+	if i.config == nil {
+		return "", errors.New("ref is not available here")
+	}
+
+	for n, k := range i.resolving {
+		if k == key {
+			i.cycle = errors.Errorf("cycle in ref: %s", strings.Join(append(i.resolving[n:], key), " -> "))
+			return "", i.cycle
+		}
+	}
+
+	entry, interpolated, ok := i.configEntry(key)
+
+	if !ok {
+		return "", errors.Errorf("%s is not a config key", key)
+	}
+
+	if entry.Ref != "" {
+		return "", errors.Errorf("%s references an external param", key)
+	}
+
+	// stage values are deployed without interpolation
+	if !interpolated {
+		return entry.Value, nil
+	}
+
+	i.resolving = append(i.resolving, key)
+	defer func() { i.resolving = i.resolving[:len(i.resolving)-1] }()
+
+	return i.interpolate(entry.Value)
+}
+
+// configEntry returns the entry of the key for the stage and whether its value
+// is interpolated
+func (i *interpolator) configEntry(key string) (rawEntry, bool, bool) {
+	if strings.HasPrefix(key, "shared/") {
+		entry, ok := i.rc.Config["shared"][strings.TrimPrefix(key, "shared/")]
+		return entry, true, ok
+	}
+
+	if entry, ok := i.rc.Config[i.config.Stage][key]; ok {
+		return entry, false, true
+	}
+
+	if entry, ok := i.rc.Config["defaults"][key]; ok {
+		return entry, true, true
+	}
+
+	entry, ok := i.rc.Config["shared"][key]
+	return entry, true, ok
+}
+
+// ssm returns the value of a param in the store of the service
+func (i *interpolator) ssm(path string) (string, error) {
+	if i.config == nil {
+		return "", errors.New("ssm is not available here")
+	}
+
+	if value, ok := i.params[path]; ok {
+		return value, nil
+	}
+
+	if i.store == nil {
+		st, err := i.getStore(i.config.StoreConfig())
+
+		if err != nil {
+			return "", errors.Wrap(err, "failed to instantiate store")
+		}
+
+		i.store = st
+	}
+
+	param, err := i.store.Get(store.ConfigInput{Name: path})
+
+	if errors.Is(err, store.ConfigNotFoundError) {
+		return "", errors.Errorf("param %s is not found", path)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	i.params[path] = *param.Value
+
+	return *param.Value, nil
+}
+
+// defaultValue returns value or the default when it is empty. The value is
+// last so that it can be piped, eg. {{env "PORT" | default "80"}}
+func defaultValue(def string, value string) string {
+	if value == "" {
+		return def
+	}
+
+	return value
+}
+
+func b64enc(value string) string {
+	return base64.StdEncoding.EncodeToString([]byte(value))
+}
+
+func join(sep string, values ...string) string {
+	return strings.Join(values, sep)
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"github.com/adikari/safebox/v2/store"
+)
+
+// Ref is a key whose value is read from a param owned by someone else. Refs
+// are resolved when configs are read and are never deployed
+type Ref struct {
+	Name   string // name of the key, as if it was deployed with the service
+	Path   string // param path or secrets manager arn
+	Secret bool
+}
+
+// Input returns the config input of the key the ref is exported as
+func (r Ref) Input() store.ConfigInput {
+	return store.ConfigInput{Name: r.Name, Secret: r.Secret}
+}
+
+// addRef records the ref. It replaces a config or ref of the same name from
+// the defaults
+func (c *Config) addRef(name string, path string, secret bool, in *interpolator) error {
+	p, err := in.interpolate(path)
+
+	if err != nil {
+		return err
+	}
+
+	c.removeRef(name)
+
+	var configs []store.ConfigInput
+	for _, input := range c.Configs {
+		if input.Name != name {
+			configs = append(configs, input)
+		}
+	}
+	c.Configs = configs
+
+	c.Refs = append(c.Refs, Ref{Name: name, Path: p, Secret: secret})
+
+	return nil
+}
+
+func (c *Config) removeRef(name string) {
+	var refs []Ref
+	for _, r := range c.Refs {
+		if r.Name != name {
+			refs = append(refs, r)
+		}
+	}
+	c.Refs = refs
+}
+
+// Ref returns the ref of the key, if it is one
+func (c *Config) Ref(name string) (Ref, bool) {
+	for _, r := range c.Refs {
+		if r.Name == name {
+			return r, true
+		}
+	}
+
+	return Ref{}, false
+}
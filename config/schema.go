@@ -0,0 +1,216 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/adikari/safebox/v2/store"
+)
+
+const (
+	TypeString = "string"
+	TypeInt    = "int"
+	TypeBool   = "bool"
+	TypeUrl    = "url"
+	TypeJson   = "json"
+	TypeEnum   = "enum"
+)
+
+// Schema of a config or secret value
+type Schema struct {
+	Type     string
+	Enum     []string
+	Pattern  *regexp.Regexp
+	Required bool
+}
+
+// required is either a bool or a list of stages the key is required in
+type required struct {
+	set    bool
+	all    bool
+	stages []string
+}
+
+func (r *required) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	r.set = true
+
+	if err := unmarshal(&r.all); err == nil {
+		return nil
+	}
+
+	return unmarshal(&r.stages)
+}
+
+func (r required) forStage(stage string, defaultValue bool) bool {
+	if !r.set {
+		return defaultValue
+	}
+
+	if r.all {
+		return true
+	}
+
+	for _, s := range r.stages {
+		if s == stage {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e rawEntry) hasSchema() bool {
+	return e.Type != "" || len(e.Enum) > 0 || e.Pattern != "" || e.Required.set
+}
+
+func newSchema(entry rawEntry, stage string, secret bool) (Schema, error) {
+	s := Schema{
+		Type:     strings.ToLower(entry.Type),
+		Enum:     entry.Enum,
+		Required: entry.Required.forStage(stage, secret),
+	}
+
+	if s.Type == "" {
+		s.Type = TypeString
+
+		if len(s.Enum) > 0 {
+			s.Type = TypeEnum
+		}
+	}
+
+	switch s.Type {
+	case TypeString, TypeInt, TypeBool, TypeUrl, TypeJson:
+	case TypeEnum:
+		if len(s.Enum) <= 0 {
+			return s, fmt.Errorf("enum type requires a list of values in 'enum'")
+		}
+	default:
+		return s, fmt.Errorf("unsupported type '%s'", entry.Type)
+	}
+
+	if entry.Pattern != "" {
+		pattern, err := regexp.Compile(entry.Pattern)
+
+		if err != nil {
+			return s, fmt.Errorf("invalid pattern: %w", err)
+		}
+
+		s.Pattern = pattern
+	}
+
+	return s, nil
+}
+
+// addSchema records the schema of the key if it declares one. A stage entry
+// without schema settings keeps the schema of the default entry
+func (c *Config) addSchema(name string, entry rawEntry, secret bool) error {
+	if !entry.hasSchema() {
+		return nil
+	}
+
+	s, err := newSchema(entry, c.Stage, secret)
+
+	if err != nil {
+		return err
+	}
+
+	c.Schemas[name] = s
+
+	return nil
+}
+
+// Validate checks the value against the schema. Empty values are only
+// rejected when the value is required
+func (s Schema) Validate(value string) error {
+	if value == "" {
+		if s.Required {
+			return fmt.Errorf("value is required")
+		}
+		return nil
+	}
+
+	switch s.Type {
+	case TypeInt:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("'%s' is not an int", value)
+		}
+	case TypeBool:
+		if value != "true" && value != "false" {
+			return fmt.Errorf("'%s' is not a bool. must be true or false", value)
+		}
+	case TypeUrl:
+		if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("'%s' is not an absolute url", value)
+		}
+	case TypeJson:
+		if !json.Valid([]byte(value)) {
+			return fmt.Errorf("value is not valid json")
+		}
+	case TypeEnum:
+		found := false
+		for _, e := range s.Enum {
+			if e == value {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("'%s' must be one of %s", value, strings.Join(s.Enum, ", "))
+		}
+	}
+
+	if s.Pattern != nil && !s.Pattern.MatchString(value) {
+		return fmt.Errorf("value does not match pattern %s", s.Pattern.String())
+	}
+
+	return nil
+}
+
+// TypescriptType is the type of the value in process.env
+func (s Schema) TypescriptType() string {
+	switch s.Type {
+	case TypeInt:
+		return "`${number}`"
+	case TypeBool:
+		return "'true' | 'false'"
+	case TypeEnum:
+		values := make([]string, len(s.Enum))
+		for i, e := range s.Enum {
+			values[i] = strconv.Quote(e)
+		}
+		return strings.Join(values, " | ")
+	default:
+		return "string"
+	}
+}
+
+// Schema returns the schema of the config or secret
+func (c *Config) Schema(input store.ConfigInput) Schema {
+	if s, ok := c.Schemas[input.Name]; ok {
+		return s
+	}
+
+	return Schema{Type: TypeString, Required: input.Secret}
+}
+
+// Validate checks the values of all configs against their schema
+func (c *Config) Validate() error {
+	var messages []string
+
+	for _, input := range c.Configs {
+		if err := c.Schema(input).Validate(input.Value); err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", input.Name, err))
+		}
+	}
+
+	if len(messages) > 0 {
+		return fmt.Errorf("invalid config values:\n%s", strings.Join(messages, "\n"))
+	}
+
+	return nil
+}
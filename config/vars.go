@@ -0,0 +1,46 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// loadVars returns the vars for the stage. The files in include are loaded in
+// order and vars in the config file take precedence over them. Include paths
+// are relative to the directory of the config file
+func loadVars(rc rawConfig, dir string, stage string) (map[string]string, error) {
+	result := map[string]string{}
+
+	for _, include := range rc.Include {
+		data, err := ioutil.ReadFile(relativeTo(dir, include))
+
+		if err != nil {
+			return nil, fmt.Errorf("missing include file %s", include)
+		}
+
+		vars := map[string]stageValue{}
+
+		if err := yaml.Unmarshal(data, &vars); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("could not parse include file %s", include))
+		}
+
+		addVars(result, vars, stage)
+	}
+
+	addVars(result, rc.Vars, stage)
+
+	return result, nil
+}
+
+// addVars adds the value of each var for the stage. Vars without a value for
+// the stage or defaults are left out
+func addVars(result map[string]string, vars map[string]stageValue, stage string) {
+	for key, value := range vars {
+		if v, ok := value.lookup(stage); ok {
+			result[key] = v
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"os"
+
 	"github.com/adikari/safebox/v2/cmd"
 )
 
@@ -9,5 +11,5 @@ var (
 )
 
 func main() {
-	cmd.Execute(version)
+	os.Exit(cmd.Execute(version))
 }
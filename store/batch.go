@@ -0,0 +1,124 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+const (
+	maxThrottleRetries = 5
+	throttleBaseDelay  = 200 * time.Millisecond
+)
+
+// BatchError collects the errors of a batch operation by parameter name
+type BatchError struct {
+	Errors map[string]error
+}
+
+func (e *BatchError) Error() string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	messages := make([]string, 0, len(names))
+	for _, name := range names {
+		messages = append(messages, fmt.Sprintf("%s: %s", name, e.Errors[name]))
+	}
+
+	return fmt.Sprintf("%d operations failed:\n%s", len(names), strings.Join(messages, "\n"))
+}
+
+func (e *BatchError) add(name string, err error) {
+	if e.Errors == nil {
+		e.Errors = map[string]error{}
+	}
+	e.Errors[name] = err
+}
+
+func (e *BatchError) errorOrNil() error {
+	if len(e.Errors) <= 0 {
+		return nil
+	}
+	return e
+}
+
+// runParallel calls fn for every item using a bounded number of workers and
+// returns the errors keyed by the item name. A BatchError returned by fn is
+// merged into the result
+func runParallel[T any](items []T, workers int, name func(T) string, fn func(T) error) error {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		batchErr BatchError
+	)
+
+	queue := make(chan T)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range queue {
+				err := fn(item)
+
+				if err == nil {
+					continue
+				}
+
+				mu.Lock()
+				if e, ok := err.(*BatchError); ok {
+					for n, err := range e.Errors {
+						batchErr.add(n, err)
+					}
+				} else {
+					batchErr.add(name(item), err)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, item := range items {
+		queue <- item
+	}
+	close(queue)
+
+	wg.Wait()
+
+	return batchErr.errorOrNil()
+}
+
+// retryThrottled retries fn with exponential backoff while aws throttles the
+// requests
+func retryThrottled(fn func() error) error {
+	delay := throttleBaseDelay
+
+	for attempt := 0; ; attempt++ {
+		err := fn()
+
+		if err == nil || attempt >= maxThrottleRetries || !isThrottled(err) {
+			return err
+		}
+
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func isThrottled(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case "ThrottlingException", "TooManyUpdates", "Throttling":
+			return true
+		}
+	}
+
+	return false
+}
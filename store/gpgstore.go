@@ -1,30 +1,73 @@
 package store
 
 import (
+	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"os/exec"
+	"os/user"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/manifoldco/promptui"
+)
+
+const (
+	gpgPassphraseEnv      = "SAFEBOX_GPG_PASSPHRASE"
+	gpgPassphraseAttempts = 3
 )
 
 var _ Store = &GpgStore{}
 
 type GpgStore struct {
-	filename string
-	path     string
+	filename   string
+	path       string
+	recipients []string
+	keyring    openpgp.EntityList
+	passphrase func() ([]byte, error)
 }
 
 type GpgStoreOptions struct {
 	Path string
+	// Recipients are user ids, emails, key ids or fingerprints the file is encrypted to
+	Recipients []string
+	// KeyFiles are armored or binary keys and keyrings. When none are given the
+	// local gpg binary is used, which reads the local keyring and gpg-agent
+	KeyFiles []string
+	// Passphrase unlocks private keys from KeyFiles. Defaults to reading
+	// SAFEBOX_GPG_PASSPHRASE or prompting
+	Passphrase func() ([]byte, error)
 }
 
 func NewGpgStore(config GpgStoreOptions) (*GpgStore, error) {
 	store := &GpgStore{
-		path: config.Path,
+		path:       config.Path,
+		recipients: config.Recipients,
+		passphrase: config.Passphrase,
+	}
+
+	if store.passphrase == nil {
+		store.passphrase = defaultPassphrase
+	}
+
+	for _, f := range config.KeyFiles {
+		keys, err := readKeyFile(f)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gpg key file %s: %w", f, err)
+		}
+
+		store.keyring = append(store.keyring, keys...)
 	}
 
 	dir := filepath.Dir(config.Path)
@@ -33,7 +76,7 @@ func NewGpgStore(config GpgStoreOptions) (*GpgStore, error) {
 		return store, nil
 	}
 
-	err := os.MkdirAll(dir, 0755)
+	err := os.MkdirAll(dir, 0700)
 
 	if err != nil {
 		return nil, err
@@ -58,28 +101,38 @@ func (s *GpgStore) PutMany(input []ConfigInput) error {
 		value := c.Value
 
 		updates = append(updates, Config{
-			Name:     &name,
-			Value:    &value,
-			Version:  "1",
-			Type:     t,
-			Created:  now,
-			Modified: now,
+			Name:       &name,
+			Value:      &value,
+			Version:    "1",
+			Type:       t,
+			Created:    now,
+			Modified:   now,
+			ModifiedBy: currentUser(),
 		})
 	}
 
-	existing, _ := s.read()
-	for _, e := range existing {
+	db, err := s.readDatabase()
+
+	if err != nil {
+		return err
+	}
+
+	for _, e := range db.Configs {
 		found, i := find(*e.Name, updates)
 		if found != nil {
 			v, _ := strconv.Atoi(e.Version)
 			found.Version = strconv.Itoa(v + 1)
+			found.Created = e.Created
 			updates[i] = *found
+			db.History = append(db.History, e)
 		} else {
 			updates = append(updates, e)
 		}
 	}
 
-	return s.write(updates)
+	db.Configs = updates
+
+	return s.write(db)
 }
 
 func (s *GpgStore) Put(input ConfigInput) error {
@@ -87,20 +140,32 @@ func (s *GpgStore) Put(input ConfigInput) error {
 }
 
 func (s *GpgStore) DeleteMany(input []ConfigInput) error {
-	existing, _ := s.read()
-	updates := []Config{}
+	db, err := s.readDatabase()
 
-	for _, e := range existing {
-		found := false
+	if err != nil {
+		return err
+	}
+
+	deleted := func(c Config) bool {
 		for _, i := range input {
-			if i.Name == *e.Name {
-				found = true
-				break
+			if i.Name == *c.Name {
+				return true
 			}
 		}
+		return false
+	}
+
+	updates := gpgDatabase{Configs: []Config{}, History: []Config{}}
+
+	for _, e := range db.Configs {
+		if !deleted(e) {
+			updates.Configs = append(updates.Configs, e)
+		}
+	}
 
-		if !found {
-			updates = append(updates, e)
+	for _, e := range db.History {
+		if !deleted(e) {
+			updates.History = append(updates.History, e)
 		}
 	}
 
@@ -119,7 +184,7 @@ func (s *GpgStore) GetMany(input []ConfigInput) ([]Config, error) {
 	existing, err := s.read()
 
 	if err != nil {
-		return nil, nil
+		return nil, err
 	}
 
 	configs := []Config{}
@@ -134,63 +199,357 @@ func (s *GpgStore) GetMany(input []ConfigInput) ([]Config, error) {
 }
 
 func (s *GpgStore) Get(input ConfigInput) (*Config, error) {
-	if configs, _ := s.GetMany([]ConfigInput{input}); configs != nil && len(configs) > 0 {
-		return &configs[0], nil
+	configs, err := s.GetMany([]ConfigInput{input})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(configs) <= 0 {
+		return nil, ConfigNotFoundError
 	}
 
-	return nil, nil
+	return &configs[0], nil
 }
 
 func (s *GpgStore) GetByPath(path string) ([]Config, error) {
-	existing, _ := s.read()
+	existing, err := s.read()
+
+	if err != nil {
+		return nil, err
+	}
+
 	result := []Config{}
 
 	for _, e := range existing {
-		if strings.HasPrefix(*e.Name, path) {
+		if isDirectChild(path, *e.Name) {
+			result = append(result, e)
+		}
+	}
+
+	return result, nil
+}
+
+func (s *GpgStore) GetHistory(input ConfigInput) ([]Config, error) {
+	db, err := s.readDatabase()
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := []Config{}
+
+	for _, e := range append(db.History, db.Configs...) {
+		if *e.Name == input.Name {
 			result = append(result, e)
 		}
 	}
 
+	if len(result) <= 0 {
+		return nil, ConfigNotFoundError
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		a, _ := strconv.Atoi(result[i].Version)
+		b, _ := strconv.Atoi(result[j].Version)
+		return a < b
+	})
+
 	return result, nil
 }
 
-// Read a record from json file
+// gpgDatabase is the content of the database file. History holds the
+// previous versions of the configs
+type gpgDatabase struct {
+	Configs []Config
+	History []Config
+}
+
 func (s *GpgStore) read() ([]Config, error) {
+	db, err := s.readDatabase()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return db.Configs, nil
+}
+
+// readDatabase decrypts the database file. Files written before encryption was
+// supported are plain json and are read as is
+func (s *GpgStore) readDatabase() (gpgDatabase, error) {
+	db := gpgDatabase{Configs: []Config{}, History: []Config{}}
+
 	if _, err := stat(s.path); err != nil {
-		return []Config{}, err
+		return db, nil
 	}
 
 	b, err := ioutil.ReadFile(s.path)
 
 	if err != nil {
-		return []Config{}, err
+		return db, err
 	}
 
-	configs := []Config{}
+	if !bytes.HasPrefix(bytes.TrimSpace(b), []byte("[")) {
+		if b, err = s.decrypt(b); err != nil {
+			return db, fmt.Errorf("failed to decrypt database: %w", err)
+		}
+	}
 
-	err = json.Unmarshal(b, &configs)
+	// databases written before history was kept only contain the configs
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("[")) {
+		err = json.Unmarshal(b, &db.Configs)
+	} else {
+		err = json.Unmarshal(b, &db)
+	}
 
 	if err != nil {
-		return nil, errors.New("failed to parse data in database")
+		return db, errors.New("failed to parse data in database")
 	}
 
-	return configs, nil
+	return db, nil
 }
 
-func (s *GpgStore) write(configs []Config) error {
-	b, err := json.MarshalIndent(configs, "", "\t")
+func (s *GpgStore) write(db gpgDatabase) error {
+	b, err := json.MarshalIndent(db, "", "\t")
 
 	if err != nil {
 		return err
 	}
 
-	if err := ioutil.WriteFile(s.path, b, 0644); err != nil {
+	if b, err = s.encrypt(b); err != nil {
+		return fmt.Errorf("failed to encrypt database: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
 		return err
 	}
 
+	return os.Rename(tmp, s.path)
+}
+
+func (s *GpgStore) encrypt(plaintext []byte) ([]byte, error) {
+	if len(s.keyring) <= 0 {
+		args := []string{"--batch", "--yes", "--armor", "--encrypt"}
+
+		if len(s.recipients) <= 0 {
+			args = append(args, "--default-recipient-self")
+		}
+
+		for _, r := range s.recipients {
+			args = append(args, "--recipient", r)
+		}
+
+		return runGpg(plaintext, args...)
+	}
+
+	to, err := s.recipientKeys()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+
+	aw, err := armor.Encode(&out, "PGP MESSAGE", nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := openpgp.Encrypt(aw, to, nil, nil, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := aw.Close(); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+func (s *GpgStore) decrypt(ciphertext []byte) ([]byte, error) {
+	if len(s.keyring.DecryptionKeys()) <= 0 {
+		return runGpg(ciphertext, "--batch", "--quiet", "--decrypt")
+	}
+
+	var r io.Reader = bytes.NewReader(ciphertext)
+
+	if block, err := armor.Decode(bytes.NewReader(ciphertext)); err == nil {
+		r = block.Body
+	}
+
+	prompted := false
+	prompt := func(keys []openpgp.Key, symmetric bool) ([]byte, error) {
+		if prompted {
+			return nil, errors.New("invalid passphrase")
+		}
+
+		prompted = true
+
+		var last []byte
+		var unlockErr error
+
+		for attempt := 0; attempt < gpgPassphraseAttempts; attempt++ {
+			passphrase, err := s.passphrase()
+
+			if err != nil {
+				return nil, err
+			}
+
+			// the passphrase is not asked again when it comes from a source
+			// that always returns the same value, eg. the environment
+			if attempt > 0 && bytes.Equal(passphrase, last) {
+				break
+			}
+
+			if unlockErr = unlockKeys(keys, passphrase); unlockErr == nil {
+				return passphrase, nil
+			}
+
+			fmt.Fprintf(os.Stderr, "failed to unlock gpg key: %s\n", unlockErr)
+			last = passphrase
+		}
+
+		return nil, fmt.Errorf("failed to unlock gpg key: %w", unlockErr)
+	}
+
+	md, err := openpgp.ReadMessage(r, s.keyring, prompt, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(md.UnverifiedBody)
+}
+
+// unlockKeys decrypts the encrypted private keys with the passphrase. It
+// succeeds when any of the keys is unlocked
+func unlockKeys(keys []openpgp.Key, passphrase []byte) error {
+	var lastErr error
+
+	for _, k := range keys {
+		if k.PrivateKey == nil {
+			continue
+		}
+
+		if !k.PrivateKey.Encrypted {
+			return nil
+		}
+
+		if err := k.PrivateKey.Decrypt(passphrase); err != nil {
+			lastErr = err
+			continue
+		}
+
+		return nil
+	}
+
+	return lastErr
+}
+
+// recipientKeys finds the keys to encrypt to. All keys in the keyring are
+// used when no recipients are configured
+func (s *GpgStore) recipientKeys() ([]*openpgp.Entity, error) {
+	if len(s.recipients) <= 0 {
+		return s.keyring, nil
+	}
+
+	var keys []*openpgp.Entity
+
+	for _, r := range s.recipients {
+		entity := findEntity(s.keyring, r)
+
+		if entity == nil {
+			return nil, fmt.Errorf("no gpg key found for recipient %s", r)
+		}
+
+		keys = append(keys, entity)
+	}
+
+	return keys, nil
+}
+
+func findEntity(keyring openpgp.EntityList, recipient string) *openpgp.Entity {
+	id := strings.ToUpper(strings.TrimPrefix(strings.ReplaceAll(recipient, " ", ""), "0x"))
+
+	for _, e := range keyring {
+		fingerprint := strings.ToUpper(hex.EncodeToString(e.PrimaryKey.Fingerprint))
+
+		if id != "" && strings.HasSuffix(fingerprint, id) {
+			return e
+		}
+
+		for name, identity := range e.Identities {
+			if name == recipient || (identity.UserId != nil && identity.UserId.Email == recipient) {
+				return e
+			}
+		}
+	}
+
 	return nil
 }
 
+func readKeyFile(path string) (openpgp.EntityList, error) {
+	b, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if keys, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(b)); err == nil {
+		return keys, nil
+	}
+
+	return openpgp.ReadKeyRing(bytes.NewReader(b))
+}
+
+func runGpg(input []byte, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("gpg", args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("gpg %s: %w", strings.TrimSpace(stderr.String()), err)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+func defaultPassphrase() ([]byte, error) {
+	if p, ok := os.LookupEnv(gpgPassphraseEnv); ok {
+		return []byte(p), nil
+	}
+
+	prompt := promptui.Prompt{
+		Label: "GPG passphrase",
+		Mask:  '*',
+	}
+
+	result, err := prompt.Run()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(result), nil
+}
+
 func find(id string, all []Config) (*Config, int) {
 	for i, c := range all {
 		if *c.Name == id {
@@ -201,6 +560,14 @@ func find(id string, all []Config) (*Config, int) {
 	return nil, -1
 }
 
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+
+	return ""
+}
+
 func stat(path string) (fi os.FileInfo, err error) {
 	if fi, err = os.Stat(path); os.IsNotExist(err) {
 		fi, err = os.Stat(path)
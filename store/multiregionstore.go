@@ -0,0 +1,200 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+)
+
+var _ Store = &MultiRegionStore{}
+var _ TagStore = &MultiRegionStore{}
+
+// RegionStore is the store of a region
+type RegionStore struct {
+	Region string
+	Store  Store
+}
+
+// MultiRegionStore replicates configs to the stores of several regions.
+// Writes go to every region. Reads go to the first region and fall back to
+// the next region when configs are missing or the region fails
+type MultiRegionStore struct {
+	regions []RegionStore
+}
+
+func NewMultiRegionStore(regions []RegionStore) *MultiRegionStore {
+	return &MultiRegionStore{regions: regions}
+}
+
+// Regions returns the stores of each region, the primary region first
+func (s *MultiRegionStore) Regions() []RegionStore {
+	return s.regions
+}
+
+func (s *MultiRegionStore) PutMany(inputs []ConfigInput) error {
+	if len(inputs) <= 0 {
+		return nil
+	}
+
+	return s.each(func(r RegionStore) error {
+		return r.Store.PutMany(inputs)
+	})
+}
+
+func (s *MultiRegionStore) Get(input ConfigInput) (*Config, error) {
+	var lastErr error = ConfigNotFoundError
+
+	for _, r := range s.regions {
+		config, err := r.Store.Get(input)
+
+		if err == nil {
+			return config, nil
+		}
+
+		if !errors.Is(err, ConfigNotFoundError) {
+			lastErr = fmt.Errorf("%s: %w", r.Region, err)
+		}
+	}
+
+	return nil, lastErr
+}
+
+// GetMany reads the configs from the first region and the configs missing
+// there from the next regions
+func (s *MultiRegionStore) GetMany(inputs []ConfigInput) ([]Config, error) {
+	result := []Config{}
+	remaining := inputs
+	succeeded := false
+
+	var lastErr error
+
+	for _, r := range s.regions {
+		if len(remaining) <= 0 {
+			break
+		}
+
+		configs, err := r.Store.GetMany(remaining)
+
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %w", r.Region, err)
+			continue
+		}
+
+		succeeded = true
+		result = append(result, configs...)
+
+		found := map[string]bool{}
+		for _, c := range configs {
+			found[*c.Name] = true
+		}
+
+		var missing []ConfigInput
+		for _, input := range remaining {
+			if !found[input.Name] {
+				missing = append(missing, input)
+			}
+		}
+
+		remaining = missing
+	}
+
+	if !succeeded && lastErr != nil {
+		return nil, lastErr
+	}
+
+	return result, nil
+}
+
+func (s *MultiRegionStore) GetByPath(path string) ([]Config, error) {
+	var lastErr error
+
+	for _, r := range s.regions {
+		configs, err := r.Store.GetByPath(path)
+
+		if err == nil {
+			return configs, nil
+		}
+
+		lastErr = fmt.Errorf("%s: %w", r.Region, err)
+	}
+
+	return nil, lastErr
+}
+
+func (s *MultiRegionStore) GetHistory(input ConfigInput) ([]Config, error) {
+	var lastErr error = ConfigNotFoundError
+
+	for _, r := range s.regions {
+		versions, err := r.Store.GetHistory(input)
+
+		if err == nil {
+			return versions, nil
+		}
+
+		if !errors.Is(err, ConfigNotFoundError) {
+			lastErr = fmt.Errorf("%s: %w", r.Region, err)
+		}
+	}
+
+	return nil, lastErr
+}
+
+func (s *MultiRegionStore) DeleteMany(inputs []ConfigInput) error {
+	if len(inputs) <= 0 {
+		return nil
+	}
+
+	return s.each(func(r RegionStore) error {
+		return r.Store.DeleteMany(inputs)
+	})
+}
+
+// GetKeyIds returns the kms keys of the first region
+func (s *MultiRegionStore) GetKeyIds(inputs []ConfigInput) (map[string]string, error) {
+	if kms, ok := s.regions[0].Store.(KmsStore); ok {
+		return kms.GetKeyIds(inputs)
+	}
+
+	return map[string]string{}, nil
+}
+
+// GetTags returns the tags of the first region
+func (s *MultiRegionStore) GetTags(inputs []ConfigInput) (map[string]map[string]string, error) {
+	if ts, ok := s.regions[0].Store.(TagStore); ok {
+		return ts.GetTags(inputs)
+	}
+
+	return map[string]map[string]string{}, nil
+}
+
+func (s *MultiRegionStore) SetTags(inputs []ConfigInput) error {
+	if len(inputs) <= 0 {
+		return nil
+	}
+
+	return s.each(func(r RegionStore) error {
+		if ts, ok := r.Store.(TagStore); ok {
+			return ts.SetTags(inputs)
+		}
+
+		return nil
+	})
+}
+
+// each calls fn for every region in parallel. Errors are keyed by the region
+func (s *MultiRegionStore) each(fn func(r RegionStore) error) error {
+	return runParallel(s.regions, len(s.regions), func(r RegionStore) string { return r.Region }, func(r RegionStore) error {
+		err := fn(r)
+
+		var batchErr *BatchError
+		if !errors.As(err, &batchErr) {
+			return err
+		}
+
+		regionErr := &BatchError{}
+		for name, e := range batchErr.Errors {
+			regionErr.add(fmt.Sprintf("%s: %s", r.Region, name), e)
+		}
+
+		return regionErr
+	})
+}
@@ -1,31 +1,54 @@
 package store
 
 import (
+	"sort"
+
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/secretsmanager"
 	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
 	"github.com/pkg/errors"
 )
 
+const defaultSecretsManagerKeyId = "alias/aws/secretsmanager"
+
 var _ Store = &SecretsManagerStore{}
+var _ KmsStore = &SecretsManagerStore{}
+var _ TagStore = &SecretsManagerStore{}
 
 type SecretsManagerStore struct {
-	svc secretsmanageriface.SecretsManagerAPI
+	svc      secretsmanageriface.SecretsManagerAPI
+	kmsKeyId string
+}
+
+type SecretsManagerStoreOptions struct {
+	// KmsKeyId encrypts the secrets. Defaults to alias/aws/secretsmanager
+	KmsKeyId string
 }
 
-func NewSecretsManagerStore(session *session.Session) (*SecretsManagerStore, error) {
-	secretsmanagerService := secretsmanager.New(session)
+func NewSecretsManagerStore(session *session.Session, options SecretsManagerStoreOptions) (*SecretsManagerStore, error) {
+	return NewSecretsManagerStoreWithClient(secretsmanager.New(session), options), nil
+}
 
+// NewSecretsManagerStoreWithClient creates the store with the given client.
+// Eg. a fake from the storetest package
+func NewSecretsManagerStoreWithClient(svc secretsmanageriface.SecretsManagerAPI, options SecretsManagerStoreOptions) *SecretsManagerStore {
 	return &SecretsManagerStore{
-		svc: secretsmanagerService,
-	}, nil
+		svc:      svc,
+		kmsKeyId: options.KmsKeyId,
+	}
 }
 
 func (s *SecretsManagerStore) Create(input ConfigInput) error {
 	param := &secretsmanager.CreateSecretInput{
 		Name:         aws.String(input.Name),
 		SecretString: aws.String(input.Value),
+		Tags:         secretsManagerTags(input.Tags),
+	}
+
+	if s.kmsKeyId != "" {
+		param.KmsKeyId = aws.String(s.kmsKeyId)
 	}
 
 	if _, err := s.svc.CreateSecret(param); err != nil {
@@ -41,17 +64,119 @@ func (s *SecretsManagerStore) Update(input ConfigInput) error {
 		SecretString: aws.String(input.Value),
 	}
 
+	if s.kmsKeyId != "" {
+		param.KmsKeyId = aws.String(s.kmsKeyId)
+	}
+
 	if _, err := s.svc.UpdateSecret(param); err != nil {
 		return errors.Wrap(err, input.Name)
 	}
 
+	return s.setTags(input)
+}
+
+// GetTags returns the tags of the secrets that exist
+func (s *SecretsManagerStore) GetTags(inputs []ConfigInput) (map[string]map[string]string, error) {
+	result := map[string]map[string]string{}
+
+	for _, input := range inputs {
+		tags, err := s.describeTags(input.Name)
+
+		if isSecretNotFound(errors.Cause(err)) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		result[input.Name] = tags
+	}
+
+	return result, nil
+}
+
+func (s *SecretsManagerStore) SetTags(inputs []ConfigInput) error {
+	for _, input := range inputs {
+		if err := s.setTags(input); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setTags adds the tags of the input and removes the tags that are no longer
+// in the input
+func (s *SecretsManagerStore) setTags(input ConfigInput) error {
+	existing, err := s.describeTags(input.Name)
+
+	if err != nil {
+		return err
+	}
+
+	if removed := removedTags(existing, input.Tags); len(removed) > 0 {
+		_, err := s.svc.UntagResource(&secretsmanager.UntagResourceInput{
+			SecretId: aws.String(input.Name),
+			TagKeys:  aws.StringSlice(removed),
+		})
+
+		if err != nil {
+			return errors.Wrap(err, input.Name)
+		}
+	}
+
+	if len(input.Tags) > 0 {
+		_, err := s.svc.TagResource(&secretsmanager.TagResourceInput{
+			SecretId: aws.String(input.Name),
+			Tags:     secretsManagerTags(input.Tags),
+		})
+
+		if err != nil {
+			return errors.Wrap(err, input.Name)
+		}
+	}
+
 	return nil
 }
 
+func (s *SecretsManagerStore) describeTags(name string) (map[string]string, error) {
+	resp, err := s.svc.DescribeSecret(&secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(name),
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, name)
+	}
+
+	tags := map[string]string{}
+	for _, tag := range resp.Tags {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	return tags, nil
+}
+
+func secretsManagerTags(tags map[string]string) []*secretsmanager.Tag {
+	var result []*secretsmanager.Tag
+
+	for _, key := range sortedTagKeys(tags) {
+		result = append(result, &secretsmanager.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(tags[key]),
+		})
+	}
+
+	return result
+}
+
 func (s *SecretsManagerStore) Put(input ConfigInput) error {
-	found, _ := s.Get(input)
+	found, err := s.Get(input)
+
+	if err != nil && !errors.Is(err, ConfigNotFoundError) {
+		return err
+	}
 
-	var err error
 	if found != nil {
 		err = s.Update(input)
 	} else {
@@ -83,7 +208,11 @@ func (s *SecretsManagerStore) Get(input ConfigInput) (*Config, error) {
 	result, err := s.svc.GetSecretValue(param)
 
 	if err != nil {
-		return nil, err
+		if isSecretNotFound(err) {
+			return nil, ConfigNotFoundError
+		}
+
+		return nil, errors.Wrap(err, input.Name)
 	}
 
 	return &Config{
@@ -104,17 +233,26 @@ func (s *SecretsManagerStore) GetMany(inputs []ConfigInput) ([]Config, error) {
 	result := []Config{}
 
 	for _, input := range inputs {
-		res, _ := s.Get(input)
-		if res != nil {
-			result = append(result, *res)
+		res, err := s.Get(input)
+
+		if errors.Is(err, ConfigNotFoundError) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
 		}
+
+		result = append(result, *res)
 	}
 
 	return result, nil
 }
 
+// GetByPath returns the secrets directly under the path. The name filter of
+// secrets manager matches prefixes, so secrets in nested paths are skipped
 func (s *SecretsManagerStore) GetByPath(path string) ([]Config, error) {
-	var result []Config
+	var names []ConfigInput
 
 	input := &secretsmanager.ListSecretsInput{
 		Filters: []*secretsmanager.Filter{
@@ -125,25 +263,108 @@ func (s *SecretsManagerStore) GetByPath(path string) ([]Config, error) {
 		},
 	}
 
-	var recursiveGet func()
-	recursiveGet = func() {
+	var recursiveGet func() error
+	recursiveGet = func() error {
 		resp, err := s.svc.ListSecrets(input)
 
 		if err != nil {
-			return
+			return err
 		}
 
 		for _, secret := range resp.SecretList {
-			result = append(result, Config{Name: secret.Name})
+			if isDirectChild(path, *secret.Name) {
+				names = append(names, ConfigInput{Name: *secret.Name})
+			}
 		}
 
 		if resp.NextToken != nil {
 			input.NextToken = resp.NextToken
-			recursiveGet()
+			return recursiveGet()
 		}
+
+		return nil
+	}
+
+	if err := recursiveGet(); err != nil {
+		return nil, err
 	}
 
-	recursiveGet()
+	return s.GetMany(names)
+}
+
+// GetKeyIds returns the kms key of the secrets
+func (s *SecretsManagerStore) GetKeyIds(inputs []ConfigInput) (map[string]string, error) {
+	result := map[string]string{}
+
+	for _, input := range inputs {
+		resp, err := s.svc.DescribeSecret(&secretsmanager.DescribeSecretInput{
+			SecretId: aws.String(input.Name),
+		})
+
+		if err != nil {
+			if isSecretNotFound(err) {
+				continue
+			}
+
+			return nil, errors.Wrap(err, input.Name)
+		}
+
+		result[*resp.Name] = aws.StringValue(resp.KmsKeyId)
+
+		if resp.KmsKeyId == nil {
+			result[*resp.Name] = defaultSecretsManagerKeyId
+		}
+	}
+
+	return result, nil
+}
+
+func (s *SecretsManagerStore) GetHistory(input ConfigInput) ([]Config, error) {
+	var versions []*secretsmanager.SecretVersionsListEntry
+
+	param := &secretsmanager.ListSecretVersionIdsInput{
+		SecretId:          aws.String(input.Name),
+		IncludeDeprecated: aws.Bool(true),
+	}
+
+	err := s.svc.ListSecretVersionIdsPages(param, func(resp *secretsmanager.ListSecretVersionIdsOutput, _ bool) bool {
+		versions = append(versions, resp.Versions...)
+		return true
+	})
+
+	if err != nil {
+		if isSecretNotFound(err) {
+			return nil, ConfigNotFoundError
+		}
+
+		return nil, errors.Wrap(err, input.Name)
+	}
+
+	result := []Config{}
+
+	for _, version := range versions {
+		value, err := s.svc.GetSecretValue(&secretsmanager.GetSecretValueInput{
+			SecretId:  aws.String(input.Name),
+			VersionId: version.VersionId,
+		})
+
+		if err != nil {
+			return nil, errors.Wrap(err, input.Name)
+		}
+
+		result = append(result, Config{
+			Name:     value.Name,
+			Value:    value.SecretString,
+			Version:  *version.VersionId,
+			Type:     "SecureString",
+			DataType: "SecureString",
+			Modified: *version.CreatedDate,
+		})
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Modified.Before(result[j].Modified)
+	})
 
 	return result, nil
 }
@@ -154,7 +375,7 @@ func (s *SecretsManagerStore) Delete(input ConfigInput) error {
 		SecretId:                   aws.String(input.Name),
 	}
 
-	if _, err := s.svc.DeleteSecret(param); err != nil {
+	if _, err := s.svc.DeleteSecret(param); err != nil && !isSecretNotFound(err) {
 		return errors.Wrap(err, input.Name)
 	}
 
@@ -174,3 +395,8 @@ func (s *SecretsManagerStore) DeleteMany(inputs []ConfigInput) error {
 
 	return nil
 }
+
+func isSecretNotFound(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == secretsmanager.ErrCodeResourceNotFoundException
+}
@@ -2,34 +2,49 @@ package store
 
 import (
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/adikari/safebox/v2/util"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
 )
 
+// ssmWorkers is kept low as parameter store allows only a few write
+// transactions per second with standard throughput
+const ssmWorkers = 3
+
 var _ Store = &SSMStore{}
+var _ KmsStore = &SSMStore{}
+var _ TagStore = &SSMStore{}
 
 type SSMStore struct {
-	svc ssmiface.SSMAPI
+	svc      ssmiface.SSMAPI
+	kmsKeyId string
 }
 
-func NewSSMStore(session *session.Session) (*SSMStore, error) {
-	svc := ssm.New(session)
+type SSMStoreOptions struct {
+	// KmsKeyId encrypts SecureString parameters. Defaults to alias/aws/ssm
+	KmsKeyId string
+}
 
-	return &SSMStore{svc: svc}, nil
+func NewSSMStore(session *session.Session, options SSMStoreOptions) (*SSMStore, error) {
+	return NewSSMStoreWithClient(ssm.New(session), options), nil
 }
 
-func (s *SSMStore) PutMany(input []ConfigInput) error {
-	for _, config := range input {
-		if err := s.Put(config); err != nil {
-			return err
-		}
-	}
+// NewSSMStoreWithClient creates the store with the given client. Eg. a fake
+// from the storetest package
+func NewSSMStoreWithClient(svc ssmiface.SSMAPI, options SSMStoreOptions) *SSMStore {
+	return &SSMStore{svc: svc, kmsKeyId: options.KmsKeyId}
+}
 
-	return nil
+// PutMany writes the configs in parallel. Each api call is retried on its own
+// when throttled so that a throttled tag call does not write another version
+func (s *SSMStore) PutMany(input []ConfigInput) error {
+	return runParallel(input, ssmWorkers, inputName, s.Put)
 }
 
 func (s *SSMStore) Put(input ConfigInput) error {
@@ -47,25 +62,156 @@ func (s *SSMStore) Put(input ConfigInput) error {
 		Overwrite:   aws.Bool(true),
 	}
 
-	_, err := s.svc.PutParameter(putParameterInput)
+	if input.Secret && s.kmsKeyId != "" {
+		putParameterInput.KeyId = aws.String(s.kmsKeyId)
+	}
+
+	var resp *ssm.PutParameterOutput
+
+	err := retryThrottled(func() (err error) {
+		resp, err = s.svc.PutParameter(putParameterInput)
+		return err
+	})
 
 	if err != nil {
 		return err
 	}
 
-	return nil
+	// new parameters have no tags to remove. tags cannot be set when
+	// overwriting a parameter
+	if aws.Int64Value(resp.Version) == 1 {
+		return s.addTags(input)
+	}
+
+	return s.setTags(input)
 }
 
-func (s *SSMStore) Delete(config ConfigInput) error {
-	if _, err := s.Get(config); err != nil {
+// GetTags returns the tags of the parameters that exist
+func (s *SSMStore) GetTags(inputs []ConfigInput) (map[string]map[string]string, error) {
+	var mu sync.Mutex
+	result := map[string]map[string]string{}
+
+	err := runParallel(inputs, ssmWorkers, inputName, func(input ConfigInput) error {
+		tags, err := s.listTags(input.Name)
+
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ssm.ErrCodeInvalidResourceId {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		result[input.Name] = tags
+		mu.Unlock()
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *SSMStore) SetTags(inputs []ConfigInput) error {
+	return runParallel(inputs, ssmWorkers, inputName, s.setTags)
+}
+
+// setTags adds the tags of the input and removes the tags that are no longer
+// in the input. Nothing is written when the tags did not change
+func (s *SSMStore) setTags(input ConfigInput) error {
+	existing, err := s.listTags(input.Name)
+
+	if err != nil {
+		return err
+	}
+
+	if !TagsDiffer(existing, input.Tags) {
+		return nil
+	}
+
+	if removed := removedTags(existing, input.Tags); len(removed) > 0 {
+		err := retryThrottled(func() error {
+			_, err := s.svc.RemoveTagsFromResource(&ssm.RemoveTagsFromResourceInput{
+				ResourceType: aws.String(ssm.ResourceTypeForTaggingParameter),
+				ResourceId:   aws.String(input.Name),
+				TagKeys:      aws.StringSlice(removed),
+			})
+			return err
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.addTags(input)
+}
+
+func (s *SSMStore) addTags(input ConfigInput) error {
+	if len(input.Tags) <= 0 {
+		return nil
+	}
+
+	return retryThrottled(func() error {
+		_, err := s.svc.AddTagsToResource(&ssm.AddTagsToResourceInput{
+			ResourceType: aws.String(ssm.ResourceTypeForTaggingParameter),
+			ResourceId:   aws.String(input.Name),
+			Tags:         ssmTags(input.Tags),
+		})
+		return err
+	})
+}
+
+func (s *SSMStore) listTags(name string) (map[string]string, error) {
+	var resp *ssm.ListTagsForResourceOutput
+
+	err := retryThrottled(func() (err error) {
+		resp, err = s.svc.ListTagsForResource(&ssm.ListTagsForResourceInput{
+			ResourceType: aws.String(ssm.ResourceTypeForTaggingParameter),
+			ResourceId:   aws.String(name),
+		})
 		return err
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
+	tags := map[string]string{}
+	for _, tag := range resp.TagList {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	return tags, nil
+}
+
+func ssmTags(tags map[string]string) []*ssm.Tag {
+	var result []*ssm.Tag
+
+	for _, key := range sortedTagKeys(tags) {
+		result = append(result, &ssm.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(tags[key]),
+		})
+	}
+
+	return result
+}
+
+func (s *SSMStore) Delete(config ConfigInput) error {
 	deleteParameterInput := &ssm.DeleteParameterInput{
 		Name: aws.String(config.Name),
 	}
 
 	if _, err := s.svc.DeleteParameter(deleteParameterInput); err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ssm.ErrCodeParameterNotFound {
+			return nil
+		}
+
 		return err
 	}
 
@@ -77,13 +223,23 @@ func (s *SSMStore) DeleteMany(configs []ConfigInput) error {
 		return nil
 	}
 
-	for _, config := range configs {
-		if err := s.Delete(config); err != nil {
-			return err
+	chunkName := func(chunk []ConfigInput) string {
+		var names []string
+		for _, c := range chunk {
+			names = append(names, c.Name)
 		}
+		return strings.Join(names, ", ")
 	}
 
-	return nil
+	// parameters that do not exist are returned as invalid and ignored
+	return runParallel(util.ChunkSlice(configs, 10), ssmWorkers, chunkName, func(chunk []ConfigInput) error {
+		return retryThrottled(func() error {
+			_, err := s.svc.DeleteParameters(&ssm.DeleteParametersInput{
+				Names: getNames(chunk),
+			})
+			return err
+		})
+	})
 }
 
 func (s *SSMStore) GetMany(configs []ConfigInput) ([]Config, error) {
@@ -133,6 +289,10 @@ func (s *SSMStore) Get(config ConfigInput) (*Config, error) {
 		return nil, err
 	}
 
+	if len(configs) <= 0 {
+		return nil, ConfigNotFoundError
+	}
+
 	return &configs[0], nil
 }
 
@@ -144,12 +304,12 @@ func (s *SSMStore) GetByPath(path string) ([]Config, error) {
 		WithDecryption: aws.Bool(true),
 	}
 
-	var recursiveGet func()
-	recursiveGet = func() {
+	var recursiveGet func() error
+	recursiveGet = func() error {
 		resp, err := s.svc.GetParametersByPath(input)
 
 		if err != nil {
-			return
+			return err
 		}
 
 		for _, param := range resp.Parameters {
@@ -158,15 +318,89 @@ func (s *SSMStore) GetByPath(path string) ([]Config, error) {
 
 		if resp.NextToken != nil {
 			input.NextToken = resp.NextToken
-			recursiveGet()
+			return recursiveGet()
+		}
+
+		return nil
+	}
+
+	if err := recursiveGet(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *SSMStore) GetHistory(config ConfigInput) ([]Config, error) {
+	var result []Config
+
+	input := &ssm.GetParameterHistoryInput{
+		Name:           aws.String(config.Name),
+		WithDecryption: aws.Bool(true),
+	}
+
+	err := s.svc.GetParameterHistoryPages(input, func(resp *ssm.GetParameterHistoryOutput, _ bool) bool {
+		for _, param := range resp.Parameters {
+			result = append(result, historyToConfig(param))
 		}
+		return true
+	})
+
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ssm.ErrCodeParameterNotFound {
+			return nil, ConfigNotFoundError
+		}
+
+		return nil, err
 	}
 
-	recursiveGet()
+	return result, nil
+}
+
+// GetKeyIds returns the kms key of the SecureString parameters
+func (s *SSMStore) GetKeyIds(inputs []ConfigInput) (map[string]string, error) {
+	result := map[string]string{}
+
+	for _, chunk := range util.ChunkSlice(inputs, 50) {
+		input := &ssm.DescribeParametersInput{
+			ParameterFilters: []*ssm.ParameterStringFilter{
+				{
+					Key:    aws.String("Name"),
+					Option: aws.String("Equals"),
+					Values: getNames(chunk),
+				},
+			},
+		}
+
+		err := s.svc.DescribeParametersPages(input, func(resp *ssm.DescribeParametersOutput, _ bool) bool {
+			for _, param := range resp.Parameters {
+				if param.KeyId != nil {
+					result[*param.Name] = *param.KeyId
+				}
+			}
+			return true
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	return result, nil
 }
 
+func historyToConfig(param *ssm.ParameterHistory) Config {
+	return Config{
+		Name:       param.Name,
+		Value:      param.Value,
+		Modified:   *param.LastModifiedDate,
+		ModifiedBy: aws.StringValue(param.LastModifiedUser),
+		Version:    fmt.Sprint(*param.Version),
+		Type:       *param.Type,
+		DataType:   aws.StringValue(param.DataType),
+	}
+}
+
 func parameterToConfig(param *ssm.Parameter) Config {
 	return Config{
 		Name:     param.Name,
@@ -178,6 +412,10 @@ func parameterToConfig(param *ssm.Parameter) Config {
 	}
 }
 
+func inputName(c ConfigInput) string {
+	return c.Name
+}
+
 func getNames(configs []ConfigInput) []*string {
 	var keys []string
 
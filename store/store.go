@@ -3,6 +3,7 @@ package store
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -12,13 +13,14 @@ import (
 )
 
 type Config struct {
-	Name     *string
-	Value    *string
-	Modified time.Time
-	Created  time.Time
-	Version  string
-	Type     string
-	DataType string
+	Name       *string
+	Value      *string
+	Modified   time.Time
+	ModifiedBy string `json:",omitempty"`
+	Created    time.Time
+	Version    string
+	Type       string
+	DataType   string
 }
 
 type ConfigInput struct {
@@ -26,6 +28,7 @@ type ConfigInput struct {
 	Value       string
 	Secret      bool
 	Description string
+	Tags        map[string]string
 }
 
 var (
@@ -37,23 +40,82 @@ type Store interface {
 	Get(input ConfigInput) (*Config, error)
 	GetMany(inputs []ConfigInput) ([]Config, error)
 	GetByPath(path string) ([]Config, error)
+	GetHistory(input ConfigInput) ([]Config, error)
 	DeleteMany(inputs []ConfigInput) error
 }
 
+// KmsStore is implemented by stores that encrypt secrets with kms keys
+type KmsStore interface {
+	GetKeyIds(inputs []ConfigInput) (map[string]string, error)
+}
+
+// TagStore is implemented by stores that keep the tags of configs
+type TagStore interface {
+	// GetTags returns the tags of the configs that exist, by name
+	GetTags(inputs []ConfigInput) (map[string]map[string]string, error)
+	// SetTags replaces the tags of the configs with the tags of the inputs.
+	// Managed tags are kept
+	SetTags(inputs []ConfigInput) error
+}
+
 type StoreConfig struct {
-	Provider string
-	Region   string
-	FilePath string
+	Provider   string
+	Region     string
+	FilePath   string
+	Recipients []string
+	KeyFiles   []string
+	KmsKeyId   string
+	// Regions to replicate to when there is more than one. Only for aws
+	// providers
+	Regions []string
+	// vault provider
+	Address   string
+	Mount     string
+	Namespace string
 }
 
 func GetStore(cfg StoreConfig) (Store, error) {
+	if util.IsAwsProvider(cfg.Provider) && len(cfg.Regions) > 1 {
+		var regions []RegionStore
+
+		for _, region := range cfg.Regions {
+			regionCfg := cfg
+			regionCfg.Region = region
+			regionCfg.Regions = nil
+
+			st, err := GetStore(regionCfg)
+
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", region, err)
+			}
+
+			regions = append(regions, RegionStore{Region: region, Store: st})
+		}
+
+		return NewMultiRegionStore(regions), nil
+	}
+
 	switch cfg.Provider {
 	case util.SsmProvider:
-		return NewSSMStore(aws.NewSession(a.Config{Region: &cfg.Region}))
+		return NewSSMStore(aws.NewSession(a.Config{Region: &cfg.Region}), SSMStoreOptions{
+			KmsKeyId: cfg.KmsKeyId,
+		})
 	case util.SecretsManagerProvider:
-		return NewSecretsManagerStore(aws.NewSession(a.Config{Region: &cfg.Region}))
+		return NewSecretsManagerStore(aws.NewSession(a.Config{Region: &cfg.Region}), SecretsManagerStoreOptions{
+			KmsKeyId: cfg.KmsKeyId,
+		})
 	case util.GpgProvider:
-		return NewGpgStore(GpgStoreOptions{Path: cfg.FilePath})
+		return NewGpgStore(GpgStoreOptions{
+			Path:       cfg.FilePath,
+			Recipients: cfg.Recipients,
+			KeyFiles:   cfg.KeyFiles,
+		})
+	case util.VaultProvider:
+		return NewVaultStore(VaultStoreOptions{
+			Address:   cfg.Address,
+			Mount:     cfg.Mount,
+			Namespace: cfg.Namespace,
+		})
 	default:
 		return nil, fmt.Errorf("invalid provider `%s`", cfg.Provider)
 	}
@@ -73,3 +135,56 @@ func (c *Config) Path() string {
 	parts := strings.Split(*c.Name, "/")
 	return strings.Join(parts[0:len(parts)-1], "/")
 }
+
+// isDirectChild reports whether name is directly under path and not in a
+// nested path
+func isDirectChild(path string, name string) bool {
+	path = strings.TrimSuffix(path, "/") + "/"
+
+	return strings.HasPrefix(name, path) && !strings.Contains(name[len(path):], "/")
+}
+
+func sortedTagKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isManagedTag reports whether the tag is set by safebox or aws instead of the
+// config file
+func isManagedTag(key string) bool {
+	return strings.HasPrefix(key, "safebox:") || strings.HasPrefix(key, "aws:")
+}
+
+// removedTags returns the keys of the existing tags that are not in tags.
+// Managed tags are never removed
+func removedTags(existing map[string]string, tags map[string]string) []string {
+	var removed []string
+
+	for _, key := range sortedTagKeys(existing) {
+		if _, ok := tags[key]; !ok && !isManagedTag(key) {
+			removed = append(removed, key)
+		}
+	}
+
+	return removed
+}
+
+// TagsDiffer reports whether the tags of the config file differ from the
+// existing tags. Managed tags are ignored
+func TagsDiffer(existing map[string]string, tags map[string]string) bool {
+	if len(removedTags(existing, tags)) > 0 {
+		return true
+	}
+
+	for key, value := range tags {
+		if v, ok := existing[key]; !ok || v != value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,271 @@
+package store_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/adikari/safebox/v2/store"
+	"github.com/adikari/safebox/v2/store/storetest"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+func TestSSMStore(t *testing.T) {
+	storetest.RunConformance(t, func(t *testing.T) store.Store {
+		return store.NewSSMStoreWithClient(storetest.NewSSM(), store.SSMStoreOptions{})
+	})
+}
+
+// throttledTagsSSM throttles the first ListTagsForResource call
+type throttledTagsSSM struct {
+	*storetest.SSM
+	throttled bool
+}
+
+func (s *throttledTagsSSM) ListTagsForResource(input *ssm.ListTagsForResourceInput) (*ssm.ListTagsForResourceOutput, error) {
+	if !s.throttled {
+		s.throttled = true
+		return nil, awserr.New("ThrottlingException", "Rate exceeded", nil)
+	}
+
+	return s.SSM.ListTagsForResource(input)
+}
+
+func TestSSMStoreRetriesThrottledTagsOnly(t *testing.T) {
+	svc := &throttledTagsSSM{SSM: storetest.NewSSM(), throttled: true}
+	st := store.NewSSMStoreWithClient(svc, store.SSMStoreOptions{})
+
+	if err := st.PutMany([]store.ConfigInput{{Name: "/svc/A", Value: "a"}}); err != nil {
+		t.Fatalf("failed to put configs: %v", err)
+	}
+
+	svc.throttled = false
+
+	if err := st.PutMany([]store.ConfigInput{{Name: "/svc/A", Value: "b", Tags: map[string]string{"team": "core"}}}); err != nil {
+		t.Fatalf("failed to put configs: %v", err)
+	}
+
+	history, err := st.GetHistory(store.ConfigInput{Name: "/svc/A"})
+
+	if err != nil || len(history) != 2 {
+		t.Fatalf("expected 2 versions, got %v, %v", len(history), err)
+	}
+
+	tags, err := st.GetTags([]store.ConfigInput{{Name: "/svc/A"}})
+
+	if err != nil || tags["/svc/A"]["team"] != "core" {
+		t.Fatalf("expected tag team=core, got %v, %v", tags, err)
+	}
+}
+
+func TestSecretsManagerStore(t *testing.T) {
+	storetest.RunConformance(t, func(t *testing.T) store.Store {
+		return store.NewSecretsManagerStoreWithClient(storetest.NewSecretsManager(), store.SecretsManagerStoreOptions{})
+	})
+}
+
+func TestMultiRegionStore(t *testing.T) {
+	storetest.RunConformance(t, func(t *testing.T) store.Store {
+		return newMultiRegionStore()
+	})
+}
+
+func TestMultiRegionStoreFallback(t *testing.T) {
+	st := newMultiRegionStore()
+	regions := st.Regions()
+
+	if err := st.PutMany([]store.ConfigInput{{Name: "/svc/A", Value: "a"}}); err != nil {
+		t.Fatalf("failed to put configs: %v", err)
+	}
+
+	for _, r := range regions {
+		if _, err := r.Store.Get(store.ConfigInput{Name: "/svc/A"}); err != nil {
+			t.Fatalf("expected /svc/A in %s, got %v", r.Region, err)
+		}
+	}
+
+	// only in the second region
+	if err := regions[1].Store.PutMany([]store.ConfigInput{{Name: "/svc/B", Value: "b"}}); err != nil {
+		t.Fatalf("failed to put configs: %v", err)
+	}
+
+	config, err := st.Get(store.ConfigInput{Name: "/svc/B"})
+
+	if err != nil || *config.Value != "b" {
+		t.Fatalf("expected /svc/B from the second region, got %v, %v", config, err)
+	}
+
+	configs, err := st.GetMany([]store.ConfigInput{{Name: "/svc/A"}, {Name: "/svc/B"}, {Name: "/svc/MISSING"}})
+
+	if err != nil || len(configs) != 2 {
+		t.Fatalf("expected configs from both regions, got %v, %v", configs, err)
+	}
+}
+
+func newMultiRegionStore() *store.MultiRegionStore {
+	return store.NewMultiRegionStore([]store.RegionStore{
+		{Region: "us-east-1", Store: store.NewSSMStoreWithClient(storetest.NewSSM(), store.SSMStoreOptions{})},
+		{Region: "us-west-2", Store: store.NewSSMStoreWithClient(storetest.NewSSM(), store.SSMStoreOptions{})},
+	})
+}
+
+func TestGpgStore(t *testing.T) {
+	keyFile := writeKeyFile(t, "")
+
+	storetest.RunConformance(t, func(t *testing.T) store.Store {
+		st, err := store.NewGpgStore(store.GpgStoreOptions{
+			Path:     filepath.Join(t.TempDir(), "db", "test"),
+			KeyFiles: []string{keyFile},
+		})
+
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+
+		return st
+	})
+}
+
+func TestGpgStoreAsksPassphraseAgain(t *testing.T) {
+	keyFile := writeKeyFile(t, "right")
+	path := filepath.Join(t.TempDir(), "db", "test")
+
+	var asked []string
+	newStore := func(passphrases ...string) *store.GpgStore {
+		st, err := store.NewGpgStore(store.GpgStoreOptions{
+			Path:     path,
+			KeyFiles: []string{keyFile},
+			Passphrase: func() ([]byte, error) {
+				p := passphrases[len(asked)%len(passphrases)]
+				asked = append(asked, p)
+				return []byte(p), nil
+			},
+		})
+
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+
+		return st
+	}
+
+	if err := newStore("right").PutMany([]store.ConfigInput{{Name: "/svc/A", Value: "a"}}); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+
+	asked = nil
+	config, err := newStore("wrong", "right").Get(store.ConfigInput{Name: "/svc/A"})
+
+	if err != nil || *config.Value != "a" {
+		t.Fatalf("expected to read with the second passphrase, got %v, %v", config, err)
+	}
+
+	if len(asked) != 2 {
+		t.Fatalf("expected passphrase to be asked twice, got %v", asked)
+	}
+
+	asked = nil
+	_, err = newStore("wrong").Get(store.ConfigInput{Name: "/svc/A"})
+
+	if err == nil || !strings.Contains(err.Error(), "failed to unlock gpg key") {
+		t.Fatalf("expected unlock error, got %v", err)
+	}
+
+	if len(asked) != 2 {
+		t.Fatalf("expected the same passphrase not to be retried, got %v", asked)
+	}
+}
+
+func TestVaultStore(t *testing.T) {
+	storetest.RunConformance(t, func(t *testing.T) store.Store {
+		server, _ := storetest.NewVaultServer("token")
+		t.Cleanup(server.Close)
+
+		st, err := store.NewVaultStore(store.VaultStoreOptions{
+			Address: server.URL,
+			Token:   "token",
+		})
+
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+
+		return st
+	})
+}
+
+func TestVaultStoreAppRoleLogin(t *testing.T) {
+	server, _ := storetest.NewVaultServer("approle-token")
+	defer server.Close()
+
+	t.Setenv("VAULT_TOKEN", "")
+
+	st, err := store.NewVaultStore(store.VaultStoreOptions{
+		Address:  server.URL,
+		RoleId:   "role",
+		SecretId: "secret",
+	})
+
+	if err != nil {
+		t.Fatalf("failed to login: %v", err)
+	}
+
+	if err := st.PutMany([]store.ConfigInput{{Name: "/svc/A", Value: "a"}}); err != nil {
+		t.Fatalf("failed to put with approle token: %v", err)
+	}
+
+	if _, err := store.NewVaultStore(store.VaultStoreOptions{
+		Address:  server.URL,
+		RoleId:   "role",
+		SecretId: "wrong",
+	}); err == nil {
+		t.Fatalf("expected login with wrong secret id to fail")
+	}
+}
+
+// writeKeyFile writes an armored private key, encrypted with the passphrase
+// unless it is empty
+func writeKeyFile(t *testing.T, passphrase string) string {
+	t.Helper()
+
+	entity, err := openpgp.NewEntity("safebox", "", "safebox@example.com", nil)
+
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	if passphrase != "" {
+		if err := entity.EncryptPrivateKeys([]byte(passphrase), nil); err != nil {
+			t.Fatalf("failed to encrypt key: %v", err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "key.asc")
+	f, err := os.Create(path)
+
+	if err != nil {
+		t.Fatalf("failed to create key file: %v", err)
+	}
+
+	defer f.Close()
+
+	w, err := armor.Encode(f, openpgp.PrivateKeyType, nil)
+
+	if err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+
+	if err := entity.SerializePrivateWithoutSigning(w, nil); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+
+	return path
+}
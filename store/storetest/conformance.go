@@ -0,0 +1,326 @@
+package storetest
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/adikari/safebox/v2/store"
+)
+
+// RunConformance runs the tests every store implementation must pass.
+// newStore must return an empty store for each test.
+func RunConformance(t *testing.T, newStore func(t *testing.T) store.Store) {
+	t.Run("get missing config", func(t *testing.T) {
+		st := newStore(t)
+
+		found, err := st.Get(store.ConfigInput{Name: "/svc/MISSING"})
+
+		if !errors.Is(err, store.ConfigNotFoundError) {
+			t.Fatalf("expected ConfigNotFoundError, got %v", err)
+		}
+
+		if found != nil {
+			t.Fatalf("expected no config, got %v", found)
+		}
+	})
+
+	t.Run("put and get", func(t *testing.T) {
+		st := newStore(t)
+
+		mustPut(t, st,
+			store.ConfigInput{Name: "/svc/CONFIG", Value: "config value"},
+			store.ConfigInput{Name: "/svc/SECRET", Value: "secret value", Secret: true},
+		)
+
+		config := mustGet(t, st, "/svc/CONFIG")
+		assertValue(t, config, "config value")
+
+		if config.Key() != "CONFIG" {
+			t.Errorf("expected key CONFIG, got %s", config.Key())
+		}
+
+		if config.Modified.IsZero() {
+			t.Errorf("expected modified time to be set")
+		}
+
+		secret := mustGet(t, st, "/svc/SECRET")
+		assertValue(t, secret, "secret value")
+
+		if secret.Type != "SecureString" {
+			t.Errorf("expected secret type SecureString, got %s", secret.Type)
+		}
+	})
+
+	t.Run("get many skips missing configs", func(t *testing.T) {
+		st := newStore(t)
+
+		mustPut(t, st, store.ConfigInput{Name: "/svc/A", Value: "a"})
+
+		configs, err := st.GetMany([]store.ConfigInput{{Name: "/svc/A"}, {Name: "/svc/MISSING"}})
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		assertNames(t, configs, "/svc/A")
+
+		configs, err = st.GetMany([]store.ConfigInput{})
+
+		if err != nil || len(configs) != 0 {
+			t.Fatalf("expected no configs, got %v, %v", configs, err)
+		}
+	})
+
+	t.Run("update bumps version", func(t *testing.T) {
+		st := newStore(t)
+
+		mustPut(t, st, store.ConfigInput{Name: "/svc/A", Value: "first"})
+		first := mustGet(t, st, "/svc/A")
+
+		mustPut(t, st, store.ConfigInput{Name: "/svc/A", Value: "second"})
+		second := mustGet(t, st, "/svc/A")
+
+		assertValue(t, second, "second")
+
+		if first.Version == "" || first.Version == second.Version {
+			t.Errorf("expected version to change, got %q and %q", first.Version, second.Version)
+		}
+	})
+
+	t.Run("history", func(t *testing.T) {
+		st := newStore(t)
+
+		mustPut(t, st, store.ConfigInput{Name: "/svc/A", Value: "first"})
+		mustPut(t, st, store.ConfigInput{Name: "/svc/A", Value: "second"})
+
+		versions, err := st.GetHistory(store.ConfigInput{Name: "/svc/A"})
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if len(versions) != 2 {
+			t.Fatalf("expected 2 versions, got %d", len(versions))
+		}
+
+		assertValue(t, &versions[0], "first")
+		assertValue(t, &versions[1], "second")
+
+		if _, err := st.GetHistory(store.ConfigInput{Name: "/svc/MISSING"}); !errors.Is(err, store.ConfigNotFoundError) {
+			t.Fatalf("expected ConfigNotFoundError, got %v", err)
+		}
+	})
+
+	t.Run("get by path", func(t *testing.T) {
+		st := newStore(t)
+
+		mustPut(t, st,
+			store.ConfigInput{Name: "/svc/A", Value: "a"},
+			store.ConfigInput{Name: "/svc/B", Value: "b", Secret: true},
+			store.ConfigInput{Name: "/svc/nested/C", Value: "c"},
+			store.ConfigInput{Name: "/svc-other/D", Value: "d"},
+		)
+
+		configs, err := st.GetByPath("/svc/")
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		assertNames(t, configs, "/svc/A", "/svc/B")
+
+		for _, c := range configs {
+			if c.Value == nil || *c.Value == "" {
+				t.Errorf("expected value for %s", *c.Name)
+			}
+		}
+	})
+
+	t.Run("get by path skips nested folders", func(t *testing.T) {
+		st := newStore(t)
+
+		mustPut(t, st,
+			store.ConfigInput{Name: "/svc/A", Value: "a"},
+			store.ConfigInput{Name: "/svc/db/B", Value: "b"},
+			store.ConfigInput{Name: "/svc/db/replica/C", Value: "c"},
+			store.ConfigInput{Name: "/stage/other/D", Value: "d"},
+		)
+
+		configs, err := st.GetByPath("/svc/")
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		assertNames(t, configs, "/svc/A")
+
+		configs, err = st.GetByPath("/stage/")
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		assertNames(t, configs)
+	})
+
+	t.Run("get by path lists more than a page", func(t *testing.T) {
+		st := newStore(t)
+
+		var inputs []store.ConfigInput
+		var names []string
+		for _, key := range []string{"A", "B", "C", "D", "E", "F", "G", "H", "I", "J", "K", "L"} {
+			inputs = append(inputs, store.ConfigInput{Name: "/svc/" + key, Value: key})
+			names = append(names, "/svc/"+key)
+		}
+
+		mustPut(t, st, inputs...)
+
+		configs, err := st.GetByPath("/svc/")
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		assertNames(t, configs, names...)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		st := newStore(t)
+
+		mustPut(t, st,
+			store.ConfigInput{Name: "/svc/A", Value: "a"},
+			store.ConfigInput{Name: "/svc/B", Value: "b"},
+		)
+
+		if err := st.DeleteMany([]store.ConfigInput{{Name: "/svc/A"}}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if _, err := st.Get(store.ConfigInput{Name: "/svc/A"}); !errors.Is(err, store.ConfigNotFoundError) {
+			t.Fatalf("expected ConfigNotFoundError after delete, got %v", err)
+		}
+
+		configs, err := st.GetByPath("/svc/")
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		assertNames(t, configs, "/svc/B")
+	})
+
+	t.Run("set tags", func(t *testing.T) {
+		st := newStore(t)
+
+		ts, ok := st.(store.TagStore)
+
+		if !ok {
+			t.Skip("store does not keep tags")
+		}
+
+		mustPut(t, st, store.ConfigInput{
+			Name:  "/svc/A",
+			Value: "a",
+			Tags:  map[string]string{"owner": "core", "team": "a", "safebox:rotated-at": "now"},
+		})
+
+		// only the tags change
+		if err := ts.SetTags([]store.ConfigInput{{Name: "/svc/A", Tags: map[string]string{"owner": "platform"}}}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		tags, err := ts.GetTags([]store.ConfigInput{{Name: "/svc/A"}, {Name: "/svc/MISSING"}})
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		expected := map[string]string{"owner": "platform", "safebox:rotated-at": "now"}
+
+		if !reflect.DeepEqual(tags["/svc/A"], expected) {
+			t.Fatalf("expected tags %v, got %v", expected, tags["/svc/A"])
+		}
+
+		if _, ok := tags["/svc/MISSING"]; ok {
+			t.Fatalf("expected no tags for missing config")
+		}
+
+		// putting a new value removes tags that are no longer declared
+		mustPut(t, st, store.ConfigInput{Name: "/svc/A", Value: "b"})
+
+		tags, err = ts.GetTags([]store.ConfigInput{{Name: "/svc/A"}})
+
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		expected = map[string]string{"safebox:rotated-at": "now"}
+
+		if !reflect.DeepEqual(tags["/svc/A"], expected) {
+			t.Fatalf("expected tags %v, got %v", expected, tags["/svc/A"])
+		}
+	})
+
+	t.Run("delete missing config", func(t *testing.T) {
+		st := newStore(t)
+
+		if err := st.DeleteMany([]store.ConfigInput{{Name: "/svc/MISSING"}}); err != nil {
+			t.Fatalf("expected deleting a missing config to succeed, got %v", err)
+		}
+
+		if err := st.DeleteMany([]store.ConfigInput{}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	})
+}
+
+func mustPut(t *testing.T, st store.Store, inputs ...store.ConfigInput) {
+	t.Helper()
+
+	if err := st.PutMany(inputs); err != nil {
+		t.Fatalf("failed to put configs: %v", err)
+	}
+}
+
+func mustGet(t *testing.T, st store.Store, name string) *store.Config {
+	t.Helper()
+
+	config, err := st.Get(store.ConfigInput{Name: name})
+
+	if err != nil {
+		t.Fatalf("failed to get %s: %v", name, err)
+	}
+
+	return config
+}
+
+func assertValue(t *testing.T, config *store.Config, expected string) {
+	t.Helper()
+
+	if config.Value == nil || *config.Value != expected {
+		t.Errorf("expected value %q for %s, got %v", expected, *config.Name, config.Value)
+	}
+}
+
+func assertNames(t *testing.T, configs []store.Config, expected ...string) {
+	t.Helper()
+
+	var names []string
+	for _, c := range configs {
+		names = append(names, *c.Name)
+	}
+	sort.Strings(names)
+	sort.Strings(expected)
+
+	if len(names) != len(expected) {
+		t.Fatalf("expected configs %v, got %v", expected, names)
+	}
+
+	for i := range names {
+		if names[i] != expected[i] {
+			t.Fatalf("expected configs %v, got %v", expected, names)
+		}
+	}
+}
@@ -0,0 +1,396 @@
+package storetest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+)
+
+const (
+	stageCurrent  = "AWSCURRENT"
+	stagePrevious = "AWSPREVIOUS"
+)
+
+var _ secretsmanageriface.SecretsManagerAPI = &SecretsManager{}
+
+// SecretsManager is an in-memory fake of the secrets manager api. Calling an
+// api that is not faked panics.
+type SecretsManager struct {
+	secretsmanageriface.SecretsManagerAPI
+
+	mu       sync.Mutex
+	secrets  map[string]*secret
+	versions int
+}
+
+type secret struct {
+	arn      string
+	kmsKeyId *string
+	tags     map[string]string
+	versions []*secretVersion
+}
+
+type secretVersion struct {
+	id      string
+	value   string
+	created time.Time
+	stages  []string
+}
+
+func NewSecretsManager() *SecretsManager {
+	return &SecretsManager{secrets: map[string]*secret{}}
+}
+
+// Tags returns the tags of the secret
+func (s *SecretsManager) Tags(name string) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := map[string]string{}
+
+	if sec, ok := s.secrets[name]; ok {
+		for key, value := range sec.tags {
+			result[key] = value
+		}
+	}
+
+	return result
+}
+
+func (s *SecretsManager) CreateSecret(input *secretsmanager.CreateSecretInput) (*secretsmanager.CreateSecretOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := aws.StringValue(input.Name)
+
+	if _, ok := s.secrets[name]; ok {
+		return nil, awserr.New(secretsmanager.ErrCodeResourceExistsException, fmt.Sprintf("the secret %s already exists", name), nil)
+	}
+
+	sec := &secret{
+		arn:      fmt.Sprintf("arn:aws:secretsmanager:us-east-1:123456789012:secret:%s-AbCdEf", name),
+		kmsKeyId: input.KmsKeyId,
+		tags:     map[string]string{},
+	}
+
+	for _, tag := range input.Tags {
+		sec.tags[*tag.Key] = *tag.Value
+	}
+
+	version := s.addVersion(sec, aws.StringValue(input.SecretString))
+	s.secrets[name] = sec
+
+	return &secretsmanager.CreateSecretOutput{
+		ARN:       aws.String(sec.arn),
+		Name:      aws.String(name),
+		VersionId: aws.String(version.id),
+	}, nil
+}
+
+func (s *SecretsManager) UpdateSecret(input *secretsmanager.UpdateSecretInput) (*secretsmanager.UpdateSecretOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name, sec, err := s.find(input.SecretId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if input.KmsKeyId != nil {
+		sec.kmsKeyId = input.KmsKeyId
+	}
+
+	output := &secretsmanager.UpdateSecretOutput{
+		ARN:  aws.String(sec.arn),
+		Name: aws.String(name),
+	}
+
+	if input.SecretString != nil {
+		output.VersionId = aws.String(s.addVersion(sec, *input.SecretString).id)
+	}
+
+	return output, nil
+}
+
+func (s *SecretsManager) GetSecretValue(input *secretsmanager.GetSecretValueInput) (*secretsmanager.GetSecretValueOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name, sec, err := s.find(input.SecretId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stage := aws.StringValue(input.VersionStage)
+	if stage == "" && input.VersionId == nil {
+		stage = stageCurrent
+	}
+
+	for _, v := range sec.versions {
+		if input.VersionId != nil && v.id != *input.VersionId {
+			continue
+		}
+
+		if stage != "" && !contains(v.stages, stage) {
+			continue
+		}
+
+		return &secretsmanager.GetSecretValueOutput{
+			ARN:           aws.String(sec.arn),
+			Name:          aws.String(name),
+			SecretString:  aws.String(v.value),
+			VersionId:     aws.String(v.id),
+			VersionStages: aws.StringSlice(v.stages),
+			CreatedDate:   aws.Time(v.created),
+		}, nil
+	}
+
+	return nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "Secrets Manager can't find the specified secret value", nil)
+}
+
+func (s *SecretsManager) DescribeSecret(input *secretsmanager.DescribeSecretInput) (*secretsmanager.DescribeSecretOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name, sec, err := s.find(input.SecretId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &secretsmanager.DescribeSecretOutput{
+		ARN:             aws.String(sec.arn),
+		Name:            aws.String(name),
+		KmsKeyId:        sec.kmsKeyId,
+		Tags:            secretTags(sec.tags),
+		LastChangedDate: aws.Time(sec.versions[len(sec.versions)-1].created),
+	}, nil
+}
+
+// ListSecrets supports the name filter, which matches the prefix of the name
+func (s *SecretsManager) ListSecrets(input *secretsmanager.ListSecretsInput) (*secretsmanager.ListSecretsOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, filter := range input.Filters {
+		if aws.StringValue(filter.Key) != secretsmanager.FilterNameStringTypeName {
+			return nil, fmt.Errorf("storetest: unsupported secrets filter %s", aws.StringValue(filter.Key))
+		}
+	}
+
+	var matched []*secretsmanager.SecretListEntry
+
+	for _, name := range s.names() {
+		if !matchesFilters(name, input.Filters) {
+			continue
+		}
+
+		sec := s.secrets[name]
+
+		matched = append(matched, &secretsmanager.SecretListEntry{
+			ARN:             aws.String(sec.arn),
+			Name:            aws.String(name),
+			KmsKeyId:        sec.kmsKeyId,
+			Tags:            secretTags(sec.tags),
+			LastChangedDate: aws.Time(sec.versions[len(sec.versions)-1].created),
+		})
+	}
+
+	page, next, err := paginate(len(matched), input.NextToken)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &secretsmanager.ListSecretsOutput{
+		SecretList: matched[page[0]:page[1]],
+		NextToken:  next,
+	}, nil
+}
+
+func (s *SecretsManager) ListSecretVersionIdsPages(input *secretsmanager.ListSecretVersionIdsInput, fn func(*secretsmanager.ListSecretVersionIdsOutput, bool) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name, sec, err := s.find(input.SecretId)
+
+	if err != nil {
+		return err
+	}
+
+	output := &secretsmanager.ListSecretVersionIdsOutput{
+		ARN:  aws.String(sec.arn),
+		Name: aws.String(name),
+	}
+
+	for _, v := range sec.versions {
+		if len(v.stages) <= 0 && !aws.BoolValue(input.IncludeDeprecated) {
+			continue
+		}
+
+		output.Versions = append(output.Versions, &secretsmanager.SecretVersionsListEntry{
+			VersionId:     aws.String(v.id),
+			VersionStages: aws.StringSlice(v.stages),
+			CreatedDate:   aws.Time(v.created),
+		})
+	}
+
+	fn(output, true)
+
+	return nil
+}
+
+func (s *SecretsManager) DeleteSecret(input *secretsmanager.DeleteSecretInput) (*secretsmanager.DeleteSecretOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name, sec, err := s.find(input.SecretId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	delete(s.secrets, name)
+
+	return &secretsmanager.DeleteSecretOutput{
+		ARN:          aws.String(sec.arn),
+		Name:         aws.String(name),
+		DeletionDate: aws.Time(time.Now()),
+	}, nil
+}
+
+func (s *SecretsManager) TagResource(input *secretsmanager.TagResourceInput) (*secretsmanager.TagResourceOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, sec, err := s.find(input.SecretId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tag := range input.Tags {
+		sec.tags[*tag.Key] = *tag.Value
+	}
+
+	return &secretsmanager.TagResourceOutput{}, nil
+}
+
+func (s *SecretsManager) UntagResource(input *secretsmanager.UntagResourceInput) (*secretsmanager.UntagResourceOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, sec, err := s.find(input.SecretId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range input.TagKeys {
+		delete(sec.tags, aws.StringValue(key))
+	}
+
+	return &secretsmanager.UntagResourceOutput{}, nil
+}
+
+// find looks up a secret by name or arn
+func (s *SecretsManager) find(id *string) (string, *secret, error) {
+	secretId := aws.StringValue(id)
+
+	if sec, ok := s.secrets[secretId]; ok {
+		return secretId, sec, nil
+	}
+
+	for name, sec := range s.secrets {
+		if sec.arn == secretId {
+			return name, sec, nil
+		}
+	}
+
+	return "", nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "Secrets Manager can't find the specified secret", nil)
+}
+
+// addVersion adds a new current version and moves the labels of the older
+// versions the way secrets manager does
+func (s *SecretsManager) addVersion(sec *secret, value string) *secretVersion {
+	s.versions++
+
+	for _, v := range sec.versions {
+		switch {
+		case contains(v.stages, stageCurrent):
+			v.stages = []string{stagePrevious}
+		case contains(v.stages, stagePrevious):
+			v.stages = nil
+		}
+	}
+
+	version := &secretVersion{
+		id:      fmt.Sprintf("00000000-0000-0000-0000-%012d", s.versions),
+		value:   value,
+		created: time.Now(),
+		stages:  []string{stageCurrent},
+	}
+
+	sec.versions = append(sec.versions, version)
+
+	return version
+}
+
+func (s *SecretsManager) names() []string {
+	names := make([]string, 0, len(s.secrets))
+	for name := range s.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func matchesFilters(name string, filters []*secretsmanager.Filter) bool {
+	for _, filter := range filters {
+		matched := false
+		for _, value := range filter.Values {
+			if strings.HasPrefix(name, *value) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+func secretTags(tags map[string]string) []*secretsmanager.Tag {
+	var result []*secretsmanager.Tag
+
+	for _, key := range sortedKeys(tags) {
+		result = append(result, &secretsmanager.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(tags[key]),
+		})
+	}
+
+	return result
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
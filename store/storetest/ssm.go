@@ -0,0 +1,387 @@
+// Package storetest provides in-memory fakes of the aws apis used by the
+// stores, so they can be tested without touching aws.
+package storetest
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+const (
+	defaultSSMKeyId = "alias/aws/ssm"
+	fakeUser        = "arn:aws:iam::123456789012:user/storetest"
+	pageSize        = 10
+)
+
+var _ ssmiface.SSMAPI = &SSM{}
+
+// SSM is an in-memory fake of the parameter store api. Calling an api that is
+// not faked panics.
+type SSM struct {
+	ssmiface.SSMAPI
+
+	mu         sync.Mutex
+	parameters map[string][]*ssm.ParameterHistory
+	tags       map[string]map[string]string
+}
+
+func NewSSM() *SSM {
+	return &SSM{
+		parameters: map[string][]*ssm.ParameterHistory{},
+		tags:       map[string]map[string]string{},
+	}
+}
+
+// Tags returns the tags of the parameter
+func (s *SSM) Tags(name string) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := map[string]string{}
+	for key, value := range s.tags[name] {
+		result[key] = value
+	}
+
+	return result
+}
+
+func (s *SSM) PutParameter(input *ssm.PutParameterInput) (*ssm.PutParameterOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := aws.StringValue(input.Name)
+	overwrite := aws.BoolValue(input.Overwrite)
+	versions := s.parameters[name]
+
+	if overwrite && len(input.Tags) > 0 {
+		return nil, awserr.New("ValidationException", "Invalid request: tags and overwrite can't be used together", nil)
+	}
+
+	if len(versions) > 0 && !overwrite {
+		return nil, awserr.New(ssm.ErrCodeParameterAlreadyExists, "The parameter already exists", nil)
+	}
+
+	paramType := aws.StringValue(input.Type)
+	if paramType == "" && len(versions) > 0 {
+		paramType = *versions[len(versions)-1].Type
+	}
+
+	param := &ssm.ParameterHistory{
+		Name:             aws.String(name),
+		Value:            aws.String(aws.StringValue(input.Value)),
+		Type:             aws.String(paramType),
+		DataType:         aws.String("text"),
+		Description:      aws.String(aws.StringValue(input.Description)),
+		Version:          aws.Int64(int64(len(versions) + 1)),
+		LastModifiedDate: aws.Time(time.Now()),
+		LastModifiedUser: aws.String(fakeUser),
+	}
+
+	if paramType == ssm.ParameterTypeSecureString {
+		param.KeyId = aws.String(defaultSSMKeyId)
+
+		if input.KeyId != nil {
+			param.KeyId = aws.String(*input.KeyId)
+		}
+	}
+
+	s.parameters[name] = append(versions, param)
+
+	if len(input.Tags) > 0 {
+		s.addTags(name, input.Tags)
+	}
+
+	return &ssm.PutParameterOutput{Version: param.Version}, nil
+}
+
+func (s *SSM) GetParameters(input *ssm.GetParametersInput) (*ssm.GetParametersOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(input.Names) > 10 {
+		return nil, awserr.New("ValidationException", "Member must have length less than or equal to 10", nil)
+	}
+
+	output := &ssm.GetParametersOutput{}
+
+	for _, name := range input.Names {
+		if param := s.latest(*name); param != nil {
+			output.Parameters = append(output.Parameters, param)
+		} else {
+			output.InvalidParameters = append(output.InvalidParameters, aws.String(*name))
+		}
+	}
+
+	return output, nil
+}
+
+func (s *SSM) GetParametersByPath(input *ssm.GetParametersByPathInput) (*ssm.GetParametersByPathOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimSuffix(aws.StringValue(input.Path), "/") + "/"
+	recursive := aws.BoolValue(input.Recursive)
+
+	var matched []*ssm.Parameter
+
+	for _, name := range s.names() {
+		if !strings.HasPrefix(name, path) {
+			continue
+		}
+
+		// parameters in nested paths are only returned when recursive
+		if !recursive && strings.Contains(name[len(path):], "/") {
+			continue
+		}
+
+		matched = append(matched, s.latest(name))
+	}
+
+	page, next, err := paginate(len(matched), input.NextToken)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ssm.GetParametersByPathOutput{
+		Parameters: matched[page[0]:page[1]],
+		NextToken:  next,
+	}, nil
+}
+
+func (s *SSM) DeleteParameter(input *ssm.DeleteParameterInput) (*ssm.DeleteParameterOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := aws.StringValue(input.Name)
+
+	if _, ok := s.parameters[name]; !ok {
+		return nil, awserr.New(ssm.ErrCodeParameterNotFound, "", nil)
+	}
+
+	delete(s.parameters, name)
+	delete(s.tags, name)
+
+	return &ssm.DeleteParameterOutput{}, nil
+}
+
+func (s *SSM) DeleteParameters(input *ssm.DeleteParametersInput) (*ssm.DeleteParametersOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(input.Names) > 10 {
+		return nil, awserr.New("ValidationException", "Member must have length less than or equal to 10", nil)
+	}
+
+	output := &ssm.DeleteParametersOutput{}
+
+	for _, name := range input.Names {
+		if _, ok := s.parameters[*name]; !ok {
+			output.InvalidParameters = append(output.InvalidParameters, aws.String(*name))
+			continue
+		}
+
+		delete(s.parameters, *name)
+		delete(s.tags, *name)
+		output.DeletedParameters = append(output.DeletedParameters, aws.String(*name))
+	}
+
+	return output, nil
+}
+
+func (s *SSM) GetParameterHistoryPages(input *ssm.GetParameterHistoryInput, fn func(*ssm.GetParameterHistoryOutput, bool) bool) error {
+	s.mu.Lock()
+	versions, ok := s.parameters[aws.StringValue(input.Name)]
+	s.mu.Unlock()
+
+	if !ok {
+		return awserr.New(ssm.ErrCodeParameterNotFound, "", nil)
+	}
+
+	var result []*ssm.ParameterHistory
+	for _, v := range versions {
+		copied := *v
+		result = append(result, &copied)
+	}
+
+	fn(&ssm.GetParameterHistoryOutput{Parameters: result}, true)
+
+	return nil
+}
+
+func (s *SSM) AddTagsToResource(input *ssm.AddTagsToResourceInput) (*ssm.AddTagsToResourceOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := aws.StringValue(input.ResourceId)
+
+	if _, ok := s.parameters[name]; !ok {
+		return nil, awserr.New(ssm.ErrCodeInvalidResourceId, "", nil)
+	}
+
+	s.addTags(name, input.Tags)
+
+	return &ssm.AddTagsToResourceOutput{}, nil
+}
+
+func (s *SSM) RemoveTagsFromResource(input *ssm.RemoveTagsFromResourceInput) (*ssm.RemoveTagsFromResourceOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := aws.StringValue(input.ResourceId)
+
+	if _, ok := s.parameters[name]; !ok {
+		return nil, awserr.New(ssm.ErrCodeInvalidResourceId, "", nil)
+	}
+
+	for _, key := range input.TagKeys {
+		delete(s.tags[name], aws.StringValue(key))
+	}
+
+	return &ssm.RemoveTagsFromResourceOutput{}, nil
+}
+
+func (s *SSM) ListTagsForResource(input *ssm.ListTagsForResourceInput) (*ssm.ListTagsForResourceOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := aws.StringValue(input.ResourceId)
+
+	if _, ok := s.parameters[name]; !ok {
+		return nil, awserr.New(ssm.ErrCodeInvalidResourceId, "", nil)
+	}
+
+	output := &ssm.ListTagsForResourceOutput{}
+
+	for _, key := range sortedKeys(s.tags[name]) {
+		output.TagList = append(output.TagList, &ssm.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(s.tags[name][key]),
+		})
+	}
+
+	return output, nil
+}
+
+// DescribeParametersPages supports the Name filter with the Equals option
+func (s *SSM) DescribeParametersPages(input *ssm.DescribeParametersInput, fn func(*ssm.DescribeParametersOutput, bool) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := s.names()
+
+	for _, filter := range input.ParameterFilters {
+		if aws.StringValue(filter.Key) != "Name" || aws.StringValue(filter.Option) != "Equals" {
+			return fmt.Errorf("storetest: unsupported parameter filter %s", filter)
+		}
+
+		var filtered []string
+		for _, name := range names {
+			for _, value := range filter.Values {
+				if name == *value {
+					filtered = append(filtered, name)
+					break
+				}
+			}
+		}
+		names = filtered
+	}
+
+	output := &ssm.DescribeParametersOutput{}
+
+	for _, name := range names {
+		versions := s.parameters[name]
+		latest := versions[len(versions)-1]
+
+		output.Parameters = append(output.Parameters, &ssm.ParameterMetadata{
+			Name:             latest.Name,
+			Type:             latest.Type,
+			DataType:         latest.DataType,
+			Description:      latest.Description,
+			KeyId:            latest.KeyId,
+			Version:          latest.Version,
+			LastModifiedDate: latest.LastModifiedDate,
+			LastModifiedUser: latest.LastModifiedUser,
+		})
+	}
+
+	fn(output, true)
+
+	return nil
+}
+
+func (s *SSM) latest(name string) *ssm.Parameter {
+	versions, ok := s.parameters[name]
+
+	if !ok {
+		return nil
+	}
+
+	v := versions[len(versions)-1]
+
+	return &ssm.Parameter{
+		Name:             aws.String(*v.Name),
+		Value:            aws.String(*v.Value),
+		Type:             aws.String(*v.Type),
+		DataType:         aws.String(*v.DataType),
+		Version:          aws.Int64(*v.Version),
+		LastModifiedDate: aws.Time(*v.LastModifiedDate),
+	}
+}
+
+func (s *SSM) names() []string {
+	names := make([]string, 0, len(s.parameters))
+	for name := range s.parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *SSM) addTags(name string, tags []*ssm.Tag) {
+	if s.tags[name] == nil {
+		s.tags[name] = map[string]string{}
+	}
+
+	for _, tag := range tags {
+		s.tags[name][*tag.Key] = *tag.Value
+	}
+}
+
+// paginate returns the bounds of the page starting at token and the token of
+// the next page
+func paginate(total int, token *string) ([2]int, *string, error) {
+	start := 0
+
+	if token != nil {
+		var err error
+		if start, err = strconv.Atoi(*token); err != nil || start > total {
+			return [2]int{}, nil, awserr.New("InvalidNextToken", "The specified token is not valid", nil)
+		}
+	}
+
+	end := start + pageSize
+	if end >= total {
+		return [2]int{start, total}, nil, nil
+	}
+
+	return [2]int{start, end}, aws.String(strconv.Itoa(end)), nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,210 @@
+package storetest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const vaultMount = "/v1/secret/"
+
+// Vault is an in-memory stand-in of the vault http api with a kv version 2
+// secrets engine mounted at secret and approle auth
+type Vault struct {
+	Token    string
+	RoleId   string
+	SecretId string
+
+	mu      sync.Mutex
+	secrets map[string][]vaultVersion
+}
+
+type vaultVersion struct {
+	data    map[string]interface{}
+	created time.Time
+}
+
+// NewVaultServer starts a server that accepts the given token, or an approle
+// login with role id "role" and secret id "secret"
+func NewVaultServer(token string) (*httptest.Server, *Vault) {
+	v := &Vault{
+		Token:    token,
+		RoleId:   "role",
+		SecretId: "secret",
+		secrets:  map[string][]vaultVersion{},
+	}
+
+	return httptest.NewServer(v), v
+}
+
+func (v *Vault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if r.URL.Path == "/v1/auth/approle/login" && r.Method == http.MethodPost {
+		v.login(w, r)
+		return
+	}
+
+	if r.Header.Get("X-Vault-Token") != v.Token {
+		writeVault(w, http.StatusForbidden, map[string]interface{}{"errors": []string{"permission denied"}})
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, vaultMount+"data/"):
+		v.data(w, r, strings.TrimPrefix(r.URL.Path, vaultMount+"data/"))
+	case strings.HasPrefix(r.URL.Path, vaultMount+"metadata/"):
+		v.metadata(w, r, strings.TrimPrefix(r.URL.Path, vaultMount+"metadata/"))
+	default:
+		writeVault(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+	}
+}
+
+func (v *Vault) login(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		RoleId   string `json:"role_id"`
+		SecretId string `json:"secret_id"`
+	}
+
+	json.NewDecoder(r.Body).Decode(&body)
+
+	if body.RoleId != v.RoleId || body.SecretId != v.SecretId {
+		writeVault(w, http.StatusBadRequest, map[string]interface{}{"errors": []string{"invalid role or secret ID"}})
+		return
+	}
+
+	writeVault(w, http.StatusOK, map[string]interface{}{
+		"auth": map[string]interface{}{"client_token": v.Token},
+	})
+}
+
+func (v *Vault) data(w http.ResponseWriter, r *http.Request, path string) {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut:
+		var body struct {
+			Data map[string]interface{} `json:"data"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeVault(w, http.StatusBadRequest, map[string]interface{}{"errors": []string{err.Error()}})
+			return
+		}
+
+		v.secrets[path] = append(v.secrets[path], vaultVersion{data: body.Data, created: time.Now().UTC()})
+		version := len(v.secrets[path])
+
+		writeVault(w, http.StatusOK, map[string]interface{}{
+			"data": versionMetadata(v.secrets[path][version-1], version),
+		})
+	case http.MethodGet:
+		versions, ok := v.secrets[path]
+
+		if !ok {
+			writeVault(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+			return
+		}
+
+		version := len(versions)
+
+		if q := r.URL.Query().Get("version"); q != "" {
+			n, err := strconv.Atoi(q)
+
+			if err != nil || n < 1 || n > len(versions) {
+				writeVault(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+				return
+			}
+
+			version = n
+		}
+
+		writeVault(w, http.StatusOK, map[string]interface{}{
+			"data": map[string]interface{}{
+				"data":     versions[version-1].data,
+				"metadata": versionMetadata(versions[version-1], version),
+			},
+		})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (v *Vault) metadata(w http.ResponseWriter, r *http.Request, path string) {
+	switch {
+	case r.Method == "LIST" || (r.Method == http.MethodGet && r.URL.Query().Get("list") == "true"):
+		keys := map[string]bool{}
+
+		for name := range v.secrets {
+			if !strings.HasPrefix(name, path) {
+				continue
+			}
+
+			rest := name[len(path):]
+
+			if i := strings.Index(rest, "/"); i >= 0 {
+				keys[rest[:i+1]] = true
+			} else {
+				keys[rest] = true
+			}
+		}
+
+		if len(keys) <= 0 {
+			writeVault(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+			return
+		}
+
+		var list []string
+		for key := range keys {
+			list = append(list, key)
+		}
+		sort.Strings(list)
+
+		writeVault(w, http.StatusOK, map[string]interface{}{
+			"data": map[string]interface{}{"keys": list},
+		})
+	case r.Method == http.MethodGet:
+		versions, ok := v.secrets[path]
+
+		if !ok {
+			writeVault(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+			return
+		}
+
+		all := map[string]interface{}{}
+		for i, version := range versions {
+			all[strconv.Itoa(i+1)] = versionMetadata(version, i+1)
+		}
+
+		writeVault(w, http.StatusOK, map[string]interface{}{
+			"data": map[string]interface{}{
+				"current_version": len(versions),
+				"versions":        all,
+			},
+		})
+	case r.Method == http.MethodDelete:
+		delete(v.secrets, path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func versionMetadata(version vaultVersion, n int) map[string]interface{} {
+	return map[string]interface{}{
+		"created_time":  version.created.Format(time.RFC3339Nano),
+		"deletion_time": "",
+		"destroyed":     false,
+		"version":       n,
+	}
+}
+
+func writeVault(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
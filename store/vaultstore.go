@@ -0,0 +1,397 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const defaultVaultMount = "secret"
+
+var _ Store = &VaultStore{}
+
+// VaultStore stores configs in a HashiCorp Vault KV version 2 secrets engine.
+// Each config is a secret at the config name under the mount
+type VaultStore struct {
+	address   string
+	mount     string
+	namespace string
+	token     string
+	client    *http.Client
+}
+
+type VaultStoreOptions struct {
+	// Address of the vault server. Defaults to VAULT_ADDR
+	Address string
+	// Mount path of the kv v2 secrets engine. Defaults to secret
+	Mount string
+	// Namespace for vault enterprise. Defaults to VAULT_NAMESPACE
+	Namespace string
+	// Token to authenticate with. Defaults to VAULT_TOKEN
+	Token string
+	// RoleId and SecretId to login with approle when there is no token.
+	// Default to VAULT_ROLE_ID and VAULT_SECRET_ID
+	RoleId   string
+	SecretId string
+	Client   *http.Client
+}
+
+// vaultData is the secret data written for each config
+type vaultData struct {
+	Value       string `json:"value"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+}
+
+type vaultMetadata struct {
+	CreatedTime  time.Time `json:"created_time"`
+	DeletionTime string    `json:"deletion_time"`
+	Destroyed    bool      `json:"destroyed"`
+	Version      int       `json:"version"`
+}
+
+func NewVaultStore(options VaultStoreOptions) (*VaultStore, error) {
+	s := &VaultStore{
+		address:   firstNonEmpty(options.Address, os.Getenv("VAULT_ADDR")),
+		mount:     strings.Trim(firstNonEmpty(options.Mount, defaultVaultMount), "/"),
+		namespace: firstNonEmpty(options.Namespace, os.Getenv("VAULT_NAMESPACE")),
+		token:     firstNonEmpty(options.Token, os.Getenv("VAULT_TOKEN")),
+		client:    options.Client,
+	}
+
+	if s.address == "" {
+		return nil, errors.New("vault address is missing. set VAULT_ADDR or vault.address")
+	}
+
+	s.address = strings.TrimSuffix(s.address, "/")
+
+	if s.client == nil {
+		s.client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	if s.token != "" {
+		return s, nil
+	}
+
+	roleId := firstNonEmpty(options.RoleId, os.Getenv("VAULT_ROLE_ID"))
+	secretId := firstNonEmpty(options.SecretId, os.Getenv("VAULT_SECRET_ID"))
+
+	if roleId == "" {
+		return nil, errors.New("vault credentials are missing. set VAULT_TOKEN or VAULT_ROLE_ID and VAULT_SECRET_ID")
+	}
+
+	if err := s.login(roleId, secretId); err != nil {
+		return nil, errors.Wrap(err, "failed to login to vault with approle")
+	}
+
+	return s, nil
+}
+
+func (s *VaultStore) PutMany(inputs []ConfigInput) error {
+	for _, input := range inputs {
+		if err := s.Put(input); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *VaultStore) Put(input ConfigInput) error {
+	t := "String"
+
+	if input.Secret {
+		t = "SecureString"
+	}
+
+	body := map[string]interface{}{
+		"data": vaultData{
+			Value:       input.Value,
+			Type:        t,
+			Description: input.Description,
+		},
+	}
+
+	if err := s.request(http.MethodPost, s.dataPath(input.Name), body, nil); err != nil {
+		return errors.Wrap(err, input.Name)
+	}
+
+	return nil
+}
+
+func (s *VaultStore) Get(input ConfigInput) (*Config, error) {
+	return s.getVersion(input.Name, 0)
+}
+
+func (s *VaultStore) GetMany(inputs []ConfigInput) ([]Config, error) {
+	result := []Config{}
+
+	for _, input := range inputs {
+		config, err := s.Get(input)
+
+		if errors.Is(err, ConfigNotFoundError) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, *config)
+	}
+
+	return result, nil
+}
+
+// GetByPath lists the secrets directly under the path. Folders are not
+// descended into, the same as the other stores
+func (s *VaultStore) GetByPath(path string) ([]Config, error) {
+	var resp struct {
+		Data struct {
+			Keys []string `json:"keys"`
+		} `json:"data"`
+	}
+
+	dir := strings.TrimSuffix(path, "/") + "/"
+
+	err := s.request("LIST", s.metadataPath(dir), nil, &resp)
+
+	if errors.Is(err, ConfigNotFoundError) {
+		return []Config{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var inputs []ConfigInput
+	for _, key := range resp.Data.Keys {
+		if !strings.HasSuffix(key, "/") {
+			inputs = append(inputs, ConfigInput{Name: dir + key})
+		}
+	}
+
+	return s.GetMany(inputs)
+}
+
+func (s *VaultStore) GetHistory(input ConfigInput) ([]Config, error) {
+	var resp struct {
+		Data struct {
+			Versions map[string]struct {
+				DeletionTime string `json:"deletion_time"`
+				Destroyed    bool   `json:"destroyed"`
+			} `json:"versions"`
+		} `json:"data"`
+	}
+
+	if err := s.request(http.MethodGet, s.metadataPath(input.Name), nil, &resp); err != nil {
+		return nil, err
+	}
+
+	var versions []int
+	for v, meta := range resp.Data.Versions {
+		version, err := strconv.Atoi(v)
+
+		if err != nil || meta.Destroyed || meta.DeletionTime != "" {
+			continue
+		}
+
+		versions = append(versions, version)
+	}
+
+	sort.Ints(versions)
+
+	result := []Config{}
+
+	for _, version := range versions {
+		config, err := s.getVersion(input.Name, version)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, *config)
+	}
+
+	if len(result) <= 0 {
+		return nil, ConfigNotFoundError
+	}
+
+	return result, nil
+}
+
+func (s *VaultStore) DeleteMany(inputs []ConfigInput) error {
+	for _, input := range inputs {
+		err := s.request(http.MethodDelete, s.metadataPath(input.Name), nil, nil)
+
+		if err != nil && !errors.Is(err, ConfigNotFoundError) {
+			return errors.Wrap(err, input.Name)
+		}
+	}
+
+	return nil
+}
+
+// getVersion reads a version of the config. Version 0 is the latest version
+func (s *VaultStore) getVersion(name string, version int) (*Config, error) {
+	var resp struct {
+		Data struct {
+			Data     *vaultData    `json:"data"`
+			Metadata vaultMetadata `json:"metadata"`
+		} `json:"data"`
+	}
+
+	path := s.dataPath(name)
+	if version > 0 {
+		path = fmt.Sprintf("%s?version=%d", path, version)
+	}
+
+	if err := s.request(http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	// the latest version is deleted or destroyed
+	if resp.Data.Data == nil {
+		return nil, ConfigNotFoundError
+	}
+
+	n := name
+	value := resp.Data.Data.Value
+
+	return &Config{
+		Name:     &n,
+		Value:    &value,
+		Modified: resp.Data.Metadata.CreatedTime,
+		Version:  strconv.Itoa(resp.Data.Metadata.Version),
+		Type:     resp.Data.Data.Type,
+		DataType: "text",
+	}, nil
+}
+
+func (s *VaultStore) login(roleId string, secretId string) error {
+	var resp struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+
+	body := map[string]string{
+		"role_id":   roleId,
+		"secret_id": secretId,
+	}
+
+	if err := s.request(http.MethodPost, "auth/approle/login", body, &resp); err != nil {
+		return err
+	}
+
+	s.token = resp.Auth.ClientToken
+
+	return nil
+}
+
+func (s *VaultStore) dataPath(name string) string {
+	return fmt.Sprintf("%s/data/%s", s.mount, escapePath(name))
+}
+
+func (s *VaultStore) metadataPath(name string) string {
+	return fmt.Sprintf("%s/metadata/%s", s.mount, escapePath(name))
+}
+
+// request calls the vault http api. A 404 response is returned as
+// ConfigNotFoundError
+func (s *VaultStore) request(method string, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+
+	if body != nil {
+		b, err := json.Marshal(body)
+
+		if err != nil {
+			return err
+		}
+
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, fmt.Sprintf("%s/v1/%s", s.address, path), reader)
+
+	if err != nil {
+		return err
+	}
+
+	if s.token != "" {
+		req.Header.Set("X-Vault-Token", s.token)
+	}
+
+	if s.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.namespace)
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.client.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ConfigNotFoundError
+	}
+
+	if resp.StatusCode >= 400 {
+		var e struct {
+			Errors []string `json:"errors"`
+		}
+
+		json.Unmarshal(b, &e)
+
+		return fmt.Errorf("vault responded with %d: %s", resp.StatusCode, strings.Join(e.Errors, ", "))
+	}
+
+	if out == nil || len(b) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(b, out)
+}
+
+// escapePath escapes each segment of a config name for use in a vault path
+func escapePath(name string) string {
+	parts := strings.Split(strings.TrimPrefix(name, "/"), "/")
+
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+
+	return strings.Join(parts, "/")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
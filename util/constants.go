@@ -4,4 +4,5 @@ const (
 	SsmProvider            = "ssm"
 	SecretsManagerProvider = "secrets-manager"
 	GpgProvider            = "gpg"
+	VaultProvider          = "vault"
 )
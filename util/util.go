@@ -1,5 +1,12 @@
 package util
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
 func ChunkSlice[T any](slice []T, chunkSize int) [][]T {
 	var chunks [][]T
 	for i := 0; i < len(slice); i += chunkSize {
@@ -26,3 +33,24 @@ func IsAwsProvider(provider string) bool {
 
 	return false
 }
+
+// ParseAge parses a duration that can also be in days. eg. 90d
+func ParseAge(age string) (time.Duration, error) {
+	if strings.HasSuffix(age, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(age, "d"))
+
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("invalid age %s", age)
+		}
+
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(age)
+
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %s", age)
+	}
+
+	return d, nil
+}